@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	"auth-service/internal/config"
+	"auth-service/internal/repository/postgres"
+	"auth-service/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// account - адрес пользователя до и после нормализации
+type account struct {
+	id        uuid.UUID
+	email     string
+	canonical string
+}
+
+// email-report находит адреса, которые не в канонической форме (service.NormalizeEmail),
+// и аккаунты, которые после нормализации совпадут. С -apply приводит к канонической
+// форме все адреса без конфликтов; конфликты нужно разрешить вручную.
+// Пока адреса с доменами IDN не переписаны, пользователи находятся по прежней форме.
+func main() {
+	apply := flag.Bool("apply", false, "rewrite non-canonical emails that have no conflicts")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("⚠️ Failed to load .env file: %v", err)
+	}
+	cfg := config.Load()
+
+	ctx := context.Background()
+	dbPool, err := postgres.NewPool(ctx, &postgres.Config{URL: cfg.DBURL})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	rows, err := dbPool.Query(ctx, `SELECT id, email FROM users ORDER BY created_at`)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	groups := make(map[string][]account)
+	var invalid []account
+	for rows.Next() {
+		var a account
+		if err := rows.Scan(&a.id, &a.email); err != nil {
+			log.Fatalf("Failed to scan user: %v", err)
+		}
+
+		a.canonical, err = service.NormalizeEmail(a.email)
+		if err != nil {
+			invalid = append(invalid, a)
+			continue
+		}
+		groups[a.canonical] = append(groups[a.canonical], a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	canonicals := make([]string, 0, len(groups))
+	for canonical := range groups {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+
+	var duplicates, rewrites []account
+	for _, canonical := range canonicals {
+		accounts := groups[canonical]
+		if len(accounts) > 1 {
+			fmt.Printf("DUPLICATE %s:\n", canonical)
+			for _, a := range accounts {
+				fmt.Printf("    %s <%s>\n", a.id, a.email)
+			}
+			duplicates = append(duplicates, accounts...)
+			continue
+		}
+		if a := accounts[0]; a.email != a.canonical {
+			fmt.Printf("REWRITE   %s <%s> -> <%s>\n", a.id, a.email, a.canonical)
+			rewrites = append(rewrites, a)
+		}
+	}
+	for _, a := range invalid {
+		fmt.Printf("INVALID   %s <%s>\n", a.id, a.email)
+	}
+
+	log.Printf("📊 %d duplicate accounts, %d emails to rewrite, %d invalid emails", len(duplicates), len(rewrites), len(invalid))
+
+	if !*apply {
+		return
+	}
+
+	for _, a := range rewrites {
+		if _, err := dbPool.Exec(ctx, `UPDATE users SET email = $2, updated_at = NOW() WHERE id = $1`, a.id, a.canonical); err != nil {
+			log.Fatalf("Failed to rewrite email of %s: %v", a.id, err)
+		}
+		if _, err := dbPool.Exec(ctx, `UPDATE user_tokens SET email = $2 WHERE user_id = $1 AND email = $3`, a.id, a.canonical, a.email); err != nil {
+			log.Fatalf("Failed to rewrite token emails of %s: %v", a.id, err)
+		}
+	}
+	log.Printf("✅ Rewrote %d emails", len(rewrites))
+}
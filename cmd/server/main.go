@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"auth-service/internal/auth"
 	"auth-service/internal/config"
+	"auth-service/internal/email"
 	"auth-service/internal/handler"
 	"auth-service/internal/middleware"
 	"auth-service/internal/repository/postgres"
@@ -39,6 +42,50 @@ func maskDBURL(dbURL string) string {
 	return parsed.String()
 }
 
+// loadKeyring загружает асимметричные ключи подписи JWT из PEM файлов
+func loadKeyring(cfg *config.Config) (*auth.Keyring, error) {
+	// Выведенные ключи принимаются, пока не истекут выданные ими токены
+	keyring := auth.NewKeyring(cfg.JWTExpiration)
+
+	for _, keyCfg := range cfg.JWTKeys {
+		state, err := auth.ParseKeyState(keyCfg.State)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyCfg.ID, err)
+		}
+
+		key, err := auth.LoadSigningKey(keyCfg.ID, state, keyCfg.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := keyring.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := keyring.SigningKey(); err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// rotateKeysOnSIGHUP продвигает next ключ в active по сигналу SIGHUP
+func rotateKeysOnSIGHUP(keyring *auth.Keyring) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := keyring.Rotate(); err != nil {
+			log.Printf("❌ JWT key rotation failed: %v", err)
+			continue
+		}
+
+		key, _ := keyring.SigningKey()
+		log.Printf("🔑 JWT signing key rotated, active kid: %s", key.ID)
+	}
+}
+
 func main() {
 	// Загрузка .env файла
 	if err := godotenv.Load(); err != nil {
@@ -50,6 +97,16 @@ func main() {
 
 	// Загрузка конфигурации
 	cfg := config.Load()
+	if err := config.RequireSecret("MFA_ENCRYPTION_KEY", cfg.MFAEncryptionKey); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := config.RequireSecret("USER_TOKEN_SECRET", cfg.UserTokenSecret); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// Ссылки из писем подписываются своим ключом: утечка одного не раскрывает другой
+	if cfg.UserTokenSecret == cfg.JWTSecret {
+		log.Fatal("Invalid configuration: USER_TOKEN_SECRET must differ from JWT_SECRET")
+	}
 
 	// Логируем маскированный URL для безопасности
 	maskedDBURL := maskDBURL(cfg.DBURL)
@@ -68,12 +125,80 @@ func main() {
 	log.Println("✅ Database connection established")
 
 	// Инициализация JWT сервиса
-	jwtService := auth.NewJWTService(cfg.JWTSecret, cfg.JWTExpiration)
+	jwtService := auth.NewJWTService(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTExpiration)
+	if len(cfg.JWTKeys) > 0 {
+		keyring, err := loadKeyring(cfg)
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+		jwtService = auth.NewJWTServiceWithKeyring(keyring, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTExpiration)
+		go rotateKeysOnSIGHUP(keyring)
+
+		log.Printf("✅ JWT keyring loaded with %d keys", len(cfg.JWTKeys))
+	}
+
+	verificationPolicy, err := service.ParseVerificationPolicy(cfg.EmailVerificationPolicy)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Инициализация репозиториев и сервисов
 	userRepo := postgres.NewUserRepository(dbPool)
-	authService := service.NewAuthService(userRepo, jwtService)
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(dbPool)
+	revocationService := service.NewRevocationService(postgres.NewRevokedTokenRepository(dbPool), userRepo)
+	userTokenRepo := postgres.NewUserTokenRepository(dbPool)
+	emailService := email.NewEmailService()
+	verificationService := service.NewEmailVerificationService(userRepo, userTokenRepo, emailService, cfg.UserTokenSecret, cfg.JWTIssuer, cfg.VerificationTokenExpiration)
+	lockoutService := service.NewLockoutService(userRepo, emailService, service.LockoutPolicy{
+		Threshold:   cfg.LoginLockoutThreshold,
+		Duration:    cfg.LoginLockoutDuration,
+		MaxDuration: cfg.LoginLockoutMaxDuration,
+	})
+	secretBox, err := auth.NewSecretBox(cfg.MFAEncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize MFA encryption: %v", err)
+	}
+	mfaService := service.NewMFAService(userRepo, userTokenRepo, lockoutService, secretBox, cfg.UserTokenSecret, cfg.MFAChallengeExpiration)
+	authService := service.NewAuthService(userRepo, jwtService, refreshTokenRepo, revocationService, verificationService, lockoutService, mfaService, verificationPolicy, cfg.RefreshTokenExpiration)
+	mfaHandler := handler.NewMFAHandler(authService, service.NewTOTPService(userRepo, mfaService, authService, secretBox, cfg.MFAIssuer))
+	passwordService := service.NewPasswordService(userRepo, refreshTokenRepo, userTokenRepo, emailService, authService, cfg.UserTokenSecret, cfg.PasswordResetURL, cfg.PasswordResetTokenExpiration)
 	authHandler := handler.NewAuthHandler(authService)
+	emailRequestRepo := postgres.NewEmailRequestRepository(dbPool)
+	magicLinkService := service.NewMagicLinkService(userRepo, userTokenRepo, emailRequestRepo, emailService, authService, authService, cfg.UserTokenSecret, cfg.MagicLinkURL, cfg.MagicLinkTokenExpiration, service.EmailRateLimit{
+		Limit:  cfg.MagicLinkRateLimit,
+		Window: cfg.MagicLinkRateWindow,
+	})
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkService, strings.HasPrefix(cfg.MagicLinkURL, "https://"))
+	emailOTPService := service.NewEmailOTPService(userRepo, postgres.NewEmailOTPRepository(dbPool), emailRequestRepo, emailService, authService, authService, cfg.UserTokenSecret, cfg.EmailOTPExpiration, cfg.EmailOTPMaxAttempts, service.EmailRateLimit{
+		Limit:  cfg.EmailOTPRateLimit,
+		Window: cfg.EmailOTPRateWindow,
+	})
+	emailOTPHandler := handler.NewEmailOTPHandler(emailOTPService)
+	verificationHandler := handler.NewVerificationHandler(verificationService)
+	emailChangeService := service.NewEmailChangeService(userRepo, refreshTokenRepo, userTokenRepo, emailService, lockoutService, emailOTPService, authService, cfg.UserTokenSecret, cfg.JWTIssuer, cfg.EmailChangeTokenExpiration, cfg.EmailChangeUndoExpiration)
+	passwordHandler := handler.NewPasswordHandler(passwordService)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeService)
+	accountService := service.NewAccountService(userRepo, refreshTokenRepo, authService, lockoutService, emailOTPService, authService, cfg.AccountDeletionGracePeriod)
+	accountHandler := handler.NewAccountHandler(accountService)
+	dataExportService := service.NewDataExportService(authService, userRepo, refreshTokenRepo, userTokenRepo, postgres.NewDataExportRepository(dbPool), emailService, cfg.UserTokenSecret, cfg.JWTIssuer, cfg.DataExportLinkExpiration, cfg.DataExportInlineBytes)
+	dataExportHandler := handler.NewDataExportHandler(dataExportService)
+	profileHandler := handler.NewProfileHandler(service.NewProfileService(userRepo, authService))
+	adminService := service.NewAdminService(userRepo, refreshTokenRepo, accountService, passwordService, lockoutService, authService)
+	adminHandler := handler.NewAdminHandler(adminService)
+	oauthService := service.NewOAuthService(postgres.NewOAuthRepository(dbPool), userRepo, authService, mfaService, jwtService, cfg.OAuthCodeExpiration)
+	introspectionService := service.NewIntrospectionService(oauthService, jwtService, revocationService)
+	oauthHandler := handler.NewOAuthHandler(oauthService, introspectionService)
+	wellKnownHandler := handler.NewWellKnownHandler(jwtService)
+	authMiddleware := middleware.AuthMiddleware(jwtService, revocationService)
+
+	// Фоновая очистка denylist от истекших токенов
+	go revocationService.StartPruning(ctx, cfg.RevokedTokenPruneInterval)
+
+	// Фоновое окончательное удаление аккаунтов после срока отмены
+	go accountService.StartPurging(ctx, cfg.AccountPurgeInterval)
+
+	// Фоновое удаление выгрузок, которые не скачали до истечения ссылки
+	go dataExportService.StartPruning(ctx, cfg.DataExportPruneInterval)
 
 	// Создание Gin роутера
 	r := gin.Default()
@@ -99,18 +224,73 @@ func main() {
 		})
 	})
 
+	// Публикация ключей и метаданных для проверки токенов другими сервисами
+	r.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+	r.GET("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+
 	// Auth routes
 	authGroup := r.Group("/auth")
 	{
 		authGroup.POST("/register", authHandler.Register)
 		authGroup.POST("/login", authHandler.Login)
+		authGroup.POST("/mfa/verify", mfaHandler.VerifyMFA)
+		authGroup.POST("/refresh", authHandler.Refresh)
+		authGroup.POST("/magic-link", magicLinkHandler.RequestMagicLink)
+		authGroup.GET("/magic-link/verify", magicLinkHandler.VerifyMagicLink)
+		authGroup.POST("/magic-link/verify", magicLinkHandler.VerifyMagicLink)
+		authGroup.POST("/otp", emailOTPHandler.RequestLoginCode)
+		authGroup.POST("/otp/verify", emailOTPHandler.LoginWithCode)
+		authGroup.POST("/logout", authMiddleware, authHandler.Logout)
+		authGroup.GET("/verify-email", verificationHandler.VerifyEmail)
+		authGroup.POST("/verify-email", verificationHandler.VerifyEmail)
+		authGroup.POST("/resend-verification", verificationHandler.ResendVerification)
+		authGroup.POST("/password/forgot", passwordHandler.ForgotPassword)
+		authGroup.POST("/password/reset", passwordHandler.ResetPassword)
+		authGroup.GET("/email/confirm", emailChangeHandler.ConfirmEmailChange)
+		authGroup.POST("/email/confirm", emailChangeHandler.ConfirmEmailChange)
+		authGroup.GET("/email/undo", emailChangeHandler.UndoEmailChange)
+		authGroup.POST("/email/undo", emailChangeHandler.UndoEmailChange)
+		authGroup.POST("/account/restore", accountHandler.RestoreAccount)
+		authGroup.GET("/account/export/download", dataExportHandler.DownloadExport)
+	}
+
+	// OAuth 2.0 authorization server (authorization code + PKCE)
+	oauthGroup := r.Group("/oauth")
+	{
+		oauthGroup.GET("/authorize", oauthHandler.AuthorizeForm)
+		oauthGroup.POST("/authorize", oauthHandler.Authorize)
+		oauthGroup.POST("/token", oauthHandler.Token)
+		oauthGroup.POST("/introspect", oauthHandler.Introspect)
 	}
 
 	// Protected routes (require JWT token)
 	protectedGroup := r.Group("/api")
-	protectedGroup.Use(middleware.AuthMiddleware(jwtService))
+	protectedGroup.Use(authMiddleware)
+	{
+		protectedGroup.GET("/profile", middleware.RequireScopes(auth.ScopeProfile), authHandler.GetProfile)
+		protectedGroup.PATCH("/profile", middleware.RequireScopes(auth.ScopeProfile), profileHandler.UpdateProfile)
+		protectedGroup.POST("/password", middleware.RequireScopes(auth.ScopeAccount), authHandler.ChangePassword)
+		protectedGroup.POST("/email", middleware.RequireScopes(auth.ScopeAccount), emailChangeHandler.RequestEmailChange)
+		protectedGroup.DELETE("/account", middleware.RequireScopes(auth.ScopeAccount), accountHandler.DeleteAccount)
+		protectedGroup.POST("/reauth/code", middleware.RequireScopes(auth.ScopeAccount), emailOTPHandler.RequestReauthCode)
+		protectedGroup.GET("/account/export", middleware.RequireScopes(auth.ScopeAccount), dataExportHandler.ExportData)
+		protectedGroup.POST("/mfa/totp", middleware.RequireScopes(auth.ScopeAccount), mfaHandler.EnrollTOTP)
+		protectedGroup.POST("/mfa/totp/confirm", middleware.RequireScopes(auth.ScopeAccount), mfaHandler.ConfirmTOTP)
+		protectedGroup.DELETE("/mfa/totp", middleware.RequireScopes(auth.ScopeAccount), mfaHandler.DisableTOTP)
+	}
+
+	// Admin routes (токены со scope admin: администраторов, вошедших напрямую, и сервисов)
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(authMiddleware, middleware.RequireAdmin(adminService))
 	{
-		protectedGroup.GET("/profile", authHandler.GetProfile)
+		adminGroup.GET("/users", adminHandler.ListUsers)
+		adminGroup.GET("/users/:id", adminHandler.GetUser)
+		adminGroup.DELETE("/users/:id", adminHandler.DeleteUser)
+		adminGroup.POST("/users/:id/suspend", adminHandler.SuspendUser)
+		adminGroup.POST("/users/:id/unsuspend", adminHandler.UnsuspendUser)
+		adminGroup.POST("/users/:id/logout", adminHandler.ForceLogout)
+		adminGroup.POST("/users/:id/password-reset", adminHandler.ForcePasswordReset)
+		adminGroup.POST("/users/:id/unlock", adminHandler.UnlockUser)
 	}
 
 	// Создаем HTTP сервер с настройками
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"auth-service/internal/backup"
+	"auth-service/internal/config"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// user-backup выгружает всех пользователей в архив (internal/backup) и восстанавливает их из архива.
+//
+//	user-backup export -output users.jsonl
+//	user-backup import -input users.jsonl [-dry-run]
+//
+// Архив содержит все колонки users, включая хеши паролей, статусы, роли и секреты TOTP.
+// Секреты TOTP зашифрованы ключом MFA_ENCRYPTION_KEY: восстанавливать архив нужно
+// на сервер с тем же ключом, иначе вход с TOTP перестанет работать. Сессии,
+// одноразовые ссылки и история статусов в архив не входят. Восстановление рассчитано
+// на пустую базу и идемпотентно: пользователи, которые уже есть, пропускаются, поэтому
+// прерванное восстановление можно запустить повторно. Перед записью архив проверяется
+// целиком. С -dry-run ничего не пишется, а печатается, что изменилось бы.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		output := flags.String("output", "users.jsonl", "archive file to create")
+		_ = flags.Parse(os.Args[2:])
+
+		exportUsers(context.Background(), newUserRepository(), *output)
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		input := flags.String("input", "users.jsonl", "archive file to restore")
+		batchSize := flags.Int("batch", 1000, "users per COPY batch")
+		dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+		_ = flags.Parse(os.Args[2:])
+		if *batchSize < 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		importUsers(context.Background(), newUserRepository(), *input, *batchSize, *dryRun)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: user-backup export -output FILE | import -input FILE [-batch N] [-dry-run]")
+	os.Exit(2)
+}
+
+// newUserRepository подключается к базе из конфигурации сервиса.
+// Пул живет до завершения процесса.
+func newUserRepository() *postgres.UserRepository {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("⚠️ Failed to load .env file: %v", err)
+	}
+	cfg := config.Load()
+
+	dbPool, err := postgres.NewPool(context.Background(), &postgres.Config{URL: cfg.DBURL})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	return postgres.NewUserRepository(dbPool)
+}
+
+// exportUsers пишет всех пользователей в архив. Недописанный архив удаляется.
+func exportUsers(ctx context.Context, repo *postgres.UserRepository, path string) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to create archive: %v", err)
+	}
+
+	writer, err := backup.NewWriter(file, time.Now())
+	if err == nil {
+		err = repo.ExportUsers(ctx, writer.Write)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		log.Fatalf("Failed to export users: %v", err)
+	}
+
+	log.Printf("📦 Exported %d users to %s", writer.Users(), path)
+}
+
+// restoreReport - итоги восстановления
+type restoreReport struct {
+	created, unchanged, differs, conflicts int
+}
+
+// importUsers проверяет архив и восстанавливает из него пользователей пачками
+func importUsers(ctx context.Context, repo *postgres.UserRepository, path string, batchSize int, dryRun bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	// Первый проход: до записи в базу убеждаемся, что архив цел
+	header, total, err := backup.Verify(file)
+	if err != nil {
+		log.Fatalf("Failed to verify archive: %v", err)
+	}
+	log.Printf("✅ Archive verified: %d users exported at %s", total, header.ExportedAt.Format(time.RFC3339))
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Fatalf("Failed to rewind archive: %v", err)
+	}
+	reader, err := backup.NewReader(file)
+	if err != nil {
+		log.Fatalf("Failed to read archive: %v", err)
+	}
+
+	report := &restoreReport{}
+	batch := make([]models.User, 0, batchSize)
+	for {
+		user, err := reader.Next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Fatalf("Failed to read archive: %v", err)
+		}
+		if user != nil {
+			batch = append(batch, *user)
+		}
+		if len(batch) == batchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			if err := restoreBatch(ctx, repo, batch, dryRun, report); err != nil {
+				log.Fatalf("Failed to restore users: %v", err)
+			}
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	verb := "created"
+	if dryRun {
+		verb = "would be created"
+	}
+	log.Printf("📊 %d users %s, %d unchanged, %d differ from the archive, %d conflict with other users", report.created, verb, report.unchanged, report.differs, report.conflicts)
+	if report.differs > 0 || report.conflicts > 0 {
+		log.Printf("⚠️ Users that differ or conflict were left as they are in the database")
+	}
+}
+
+// restoreBatch вставляет пачку и разбирает, почему пропущены невставленные пользователи
+func restoreBatch(ctx context.Context, repo *postgres.UserRepository, batch []models.User, dryRun bool, report *restoreReport) error {
+	inserted, err := repo.RestoreUsers(ctx, batch, dryRun)
+	if err != nil {
+		return err
+	}
+	report.created += len(inserted)
+
+	insertedIDs := make(map[uuid.UUID]bool, len(inserted))
+	for _, id := range inserted {
+		insertedIDs[id] = true
+	}
+	var skipped []uuid.UUID
+	for _, user := range batch {
+		if !insertedIDs[user.ID] {
+			skipped = append(skipped, user.ID)
+		}
+	}
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	existing, err := repo.GetUsersByIDs(ctx, skipped)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.User, len(existing))
+	for i := range existing {
+		byID[existing[i].ID] = &existing[i]
+	}
+
+	for i := range batch {
+		user := &batch[i]
+		if insertedIDs[user.ID] {
+			continue
+		}
+
+		current, ok := byID[user.ID]
+		switch {
+		case !ok:
+			// id свободен, но email или username заняты другим пользователем
+			fmt.Printf("CONFLICT  %s <%s>\n", user.ID, user.Email)
+			report.conflicts++
+		case backup.Equal(current, user):
+			report.unchanged++
+		default:
+			fmt.Printf("DIFFERS   %s <%s>\n", user.ID, user.Email)
+			report.differs++
+		}
+	}
+
+	return nil
+}
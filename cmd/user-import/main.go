@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/config"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"auth-service/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// Результаты обработки записи в файле отчета
+const (
+	outcomeDuplicate = "duplicate"
+	outcomeRejected  = "rejected"
+)
+
+// record - пользователь из файла импорта
+type record struct {
+	line            int
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"password_hash"`
+	Username        string     `json:"username"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       *time.Time `json:"created_at"`
+}
+
+// recordReader читает записи по одной; в конце файла возвращает io.EOF.
+// Ошибка *recordError относится к одной записи, чтение можно продолжать.
+type recordReader interface {
+	Next() (*record, error)
+}
+
+// recordError - запись, которую не удалось разобрать
+type recordError struct {
+	line int
+	err  error
+}
+
+func (e *recordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// user-import загружает пользователей из CSV или JSONL пачками через COPY.
+// Адреса нормализуются (service.NormalizeEmail), хеши паролей принимаются в форматах
+// bcrypt, argon2id, scrypt и pbkdf2_sha256 (Django) и заменяются на bcrypt при первом входе.
+// Дубликаты и отклоненные записи пишутся в файл отчета.
+//
+// Колонки CSV (первая строка - заголовок) и ключи JSONL: email, password_hash,
+// username, email_verified_at, created_at (RFC 3339). Обязательны email и password_hash.
+func main() {
+	file := flag.String("file", "", "CSV or JSONL file with users")
+	format := flag.String("format", "", "input format: csv or jsonl (default: by file extension)")
+	batchSize := flag.Int("batch", 1000, "users per COPY batch")
+	summaryPath := flag.String("summary", "import-summary.csv", "file for duplicates and rejected records")
+	dryRun := flag.Bool("dry-run", false, "validate the file without writing to the database")
+	flag.Parse()
+
+	if *file == "" || *batchSize < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open input: %v", err)
+	}
+	defer input.Close()
+
+	var reader recordReader
+	switch *format {
+	case "csv":
+		reader, err = newCSVReader(input)
+	case "jsonl":
+		reader = newJSONLReader(input)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+
+	summaryFile, err := os.Create(*summaryPath)
+	if err != nil {
+		log.Fatalf("Failed to create summary: %v", err)
+	}
+	defer summaryFile.Close()
+	summary := csv.NewWriter(summaryFile)
+	_ = summary.Write([]string{"line", "email", "outcome", "reason"})
+
+	importer := &importer{
+		batchSize: *batchSize,
+		summary:   summary,
+		emails:    make(map[string]int),
+		usernames: make(map[string]int),
+	}
+
+	if !*dryRun {
+		if err := godotenv.Load(); err != nil {
+			log.Printf("⚠️ Failed to load .env file: %v", err)
+		}
+		cfg := config.Load()
+
+		ctx := context.Background()
+		dbPool, err := postgres.NewPool(ctx, &postgres.Config{URL: cfg.DBURL})
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer dbPool.Close()
+
+		importer.repo = postgres.NewUserRepository(dbPool)
+	}
+
+	if err := importer.run(context.Background(), reader); err != nil {
+		log.Fatalf("Import failed after %d users: %v", importer.imported, err)
+	}
+
+	summary.Flush()
+	if err := summary.Error(); err != nil {
+		log.Fatalf("Failed to write summary: %v", err)
+	}
+
+	log.Printf("📊 %d imported, %d duplicates, %d rejected (details in %s)", importer.imported, importer.duplicates, importer.rejected, *summaryPath)
+}
+
+// importer проверяет записи и вставляет их пачками
+type importer struct {
+	repo      *postgres.UserRepository
+	batchSize int
+	summary   *csv.Writer
+
+	// Строки, на которых адрес и username встретились впервые
+	emails    map[string]int
+	usernames map[string]int
+
+	batch []models.User
+	lines map[uuid.UUID]int
+
+	imported, duplicates, rejected int
+}
+
+func (im *importer) run(ctx context.Context, reader recordReader) error {
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var recErr *recordError
+		if errors.As(err, &recErr) {
+			im.report(recErr.line, "", outcomeRejected, recErr.err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		im.add(rec)
+		if len(im.batch) >= im.batchSize {
+			if err := im.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return im.flush(ctx)
+}
+
+// add проверяет запись и добавляет ее в текущую пачку
+func (im *importer) add(rec *record) {
+	email, err := service.NormalizeEmail(rec.Email)
+	if err != nil {
+		im.report(rec.line, rec.Email, outcomeRejected, err.Error())
+		return
+	}
+	if _, err := auth.PasswordHashScheme(rec.PasswordHash); err != nil {
+		im.report(rec.line, email, outcomeRejected, err.Error())
+		return
+	}
+	if rec.Username != "" {
+		if err := service.ValidateUsername(rec.Username); err != nil {
+			im.report(rec.line, email, outcomeRejected, err.Error())
+			return
+		}
+	}
+
+	if first, ok := im.emails[email]; ok {
+		im.report(rec.line, email, outcomeDuplicate, fmt.Sprintf("email already seen on line %d", first))
+		return
+	}
+	username := strings.ToLower(rec.Username)
+	if first, ok := im.usernames[username]; ok && username != "" {
+		im.report(rec.line, email, outcomeDuplicate, fmt.Sprintf("username already seen on line %d", first))
+		return
+	}
+	im.emails[email] = rec.line
+	if username != "" {
+		im.usernames[username] = rec.line
+	}
+
+	user := models.User{
+		ID:              uuid.New(),
+		Email:           email,
+		PasswordHash:    rec.PasswordHash,
+		EmailVerifiedAt: rec.EmailVerifiedAt,
+		Status:          models.UserStatusPending,
+		CreatedAt:       time.Now(),
+	}
+	if rec.EmailVerifiedAt != nil {
+		user.Status = models.UserStatusActive
+	}
+	reason := models.StatusReasonImported
+	user.StatusReason = &reason
+	if rec.Username != "" {
+		user.Username = &rec.Username
+	}
+	if rec.CreatedAt != nil {
+		user.CreatedAt = *rec.CreatedAt
+	}
+
+	if im.lines == nil {
+		im.lines = make(map[uuid.UUID]int)
+	}
+	im.batch = append(im.batch, user)
+	im.lines[user.ID] = rec.line
+}
+
+// flush вставляет накопленную пачку. Записи, не вставленные из-за конфликта
+// с существующими пользователями, попадают в отчет как дубликаты.
+func (im *importer) flush(ctx context.Context) error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+
+	if im.repo == nil {
+		im.imported += len(im.batch)
+	} else {
+		inserted, err := im.repo.ImportUsers(ctx, im.batch)
+		if err != nil {
+			return err
+		}
+
+		insertedIDs := make(map[uuid.UUID]bool, len(inserted))
+		for _, id := range inserted {
+			insertedIDs[id] = true
+		}
+		for _, user := range im.batch {
+			if !insertedIDs[user.ID] {
+				im.report(im.lines[user.ID], user.Email, outcomeDuplicate, "email or username already registered")
+			}
+		}
+		im.imported += len(inserted)
+		log.Printf("📥 Imported %d users", im.imported)
+	}
+
+	im.batch = im.batch[:0]
+	clear(im.lines)
+	return nil
+}
+
+// report записывает запись в отчет
+func (im *importer) report(line int, email, outcome, reason string) {
+	if outcome == outcomeDuplicate {
+		im.duplicates++
+	} else {
+		im.rejected++
+	}
+	_ = im.summary.Write([]string{strconv.Itoa(line), email, outcome, reason})
+}
+
+// csvReader читает CSV с заголовком
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func newCSVReader(input io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(bufio.NewReader(input))
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, required := range []string{"email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %q column", required)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns, line: 1}, nil
+}
+
+func (r *csvReader) Next() (*record, error) {
+	fields, err := r.reader.Read()
+	r.line++
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if errors.Is(err, csv.ErrFieldCount) {
+		return nil, &recordError{line: r.line, err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	rec := &record{
+		line:         r.line,
+		Email:        field("email"),
+		PasswordHash: field("password_hash"),
+		Username:     field("username"),
+	}
+	if rec.EmailVerifiedAt, err = parseOptionalTime(field("email_verified_at")); err != nil {
+		return nil, &recordError{line: r.line, err: fmt.Errorf("email_verified_at: %w", err)}
+	}
+	if rec.CreatedAt, err = parseOptionalTime(field("created_at")); err != nil {
+		return nil, &recordError{line: r.line, err: fmt.Errorf("created_at: %w", err)}
+	}
+
+	return rec, nil
+}
+
+// jsonlReader читает по одному JSON объекту на строку
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(input io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (r *jsonlReader) Next() (*record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		rec := &record{line: r.line}
+		if err := json.Unmarshal([]byte(line), rec); err != nil {
+			return nil, &recordError{line: r.line, err: err}
+		}
+		return rec, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// parseOptionalTime разбирает время в RFC 3339; пустая строка - нет значения
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
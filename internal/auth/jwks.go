@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK - публичный ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS - набор публичных ключей для /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает публичные ключи, которыми можно проверить выданные токены.
+// В режиме общего секрета (HS256) набор пустой - секрет не публикуется.
+func (j *JWTService) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if j.keyring == nil {
+		return jwks
+	}
+
+	for _, key := range j.keyring.PublicKeys() {
+		if jwk, ok := toJWK(key); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// SigningAlgorithms возвращает алгоритмы active и next ключей - ими подписываются текущие
+// и будущие токены. Публикуется в id_token_signing_alg_values_supported (OIDC Discovery, REQUIRED).
+func (j *JWTService) SigningAlgorithms() []string {
+	if j.keyring == nil {
+		return []string{"HS256"}
+	}
+
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range j.keyring.PublicKeys() {
+		if key.State != KeyStateActive && key.State != KeyStateNext {
+			continue
+		}
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// toJWK кодирует публичный ключ в JWK
+func toJWK(key *SigningKey) (JWK, bool) {
+	jwk := JWK{
+		Use: "sig",
+		Kid: key.ID,
+		Alg: key.Method.Alg(),
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64URL(pub.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, false
+		}
+		// Несжатая точка: 0x04 || X || Y, координаты фиксированной длины
+		raw := point.Bytes()[1:]
+		size := len(raw) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64URL(raw[:size])
+		jwk.Y = base64URL(raw[size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64URL(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTService_JWKS_PublishesAcceptedKeys(t *testing.T) {
+	keyring := NewKeyring(time.Hour)
+	rsaKey := newTestKey(t, "rsa", KeyStateActive, "RS256")
+	require.NoError(t, keyring.Add(rsaKey))
+	require.NoError(t, keyring.Add(newTestKey(t, "ec", KeyStateNext, "ES256")))
+	require.NoError(t, keyring.Add(newTestKey(t, "ed", KeyStateNext, "EdDSA")))
+	jwtService := NewJWTServiceWithKeyring(keyring, testIssuer, testAudience, time.Hour)
+
+	jwks := jwtService.JWKS()
+	require.Len(t, jwks.Keys, 3)
+
+	byKid := make(map[string]JWK)
+	for _, jwk := range jwks.Keys {
+		byKid[jwk.Kid] = jwk
+	}
+
+	// RSA ключ восстанавливается из n и e
+	n, err := base64.RawURLEncoding.DecodeString(byKid["rsa"].N)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(byKid["rsa"].E)
+	require.NoError(t, err)
+	pub := rsaKey.Public.(*rsa.PublicKey)
+	assert.Equal(t, 0, pub.N.Cmp(new(big.Int).SetBytes(n)))
+	assert.Equal(t, int64(pub.E), new(big.Int).SetBytes(e).Int64())
+	assert.Equal(t, "RS256", byKid["rsa"].Alg)
+
+	// Координаты EC ключа имеют фиксированную длину кривой
+	x, err := base64.RawURLEncoding.DecodeString(byKid["ec"].X)
+	require.NoError(t, err)
+	assert.Len(t, x, 32)
+	assert.Equal(t, "P-256", byKid["ec"].Crv)
+
+	assert.Equal(t, "OKP", byKid["ed"].Kty)
+	assert.ElementsMatch(t, []string{"RS256", "ES256", "EdDSA"}, jwtService.SigningAlgorithms())
+
+	// Выведенный ключ больше не публикуется
+	require.NoError(t, keyring.Retire("ec"))
+	assert.Len(t, jwtService.JWKS().Keys, 2)
+	assert.ElementsMatch(t, []string{"RS256", "EdDSA"}, jwtService.SigningAlgorithms())
+}
+
+func TestJWTService_JWKS_EmptyForSharedSecret(t *testing.T) {
+	jwtService := NewJWTService("secret", testIssuer, testAudience, time.Hour)
+
+	assert.Empty(t, jwtService.JWKS().Keys)
+	assert.Equal(t, []string{"HS256"}, jwtService.SigningAlgorithms())
+}
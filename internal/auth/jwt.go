@@ -2,56 +2,186 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTService struct {
 	secretKey  []byte
+	keyring    *Keyring
+	issuer     string
+	audience   string
 	expiration time.Duration
 }
 
-func NewJWTService(secretKey string, expiration time.Duration) *JWTService {
+// NewJWTService создает сервис, подписывающий токены общим секретом (HS256)
+func NewJWTService(secretKey, issuer, audience string, expiration time.Duration) *JWTService {
 	return &JWTService{
 		secretKey:  []byte(secretKey),
+		issuer:     issuer,
+		audience:   audience,
 		expiration: expiration,
 	}
 }
 
+// NewJWTServiceWithKeyring создает сервис, подписывающий токены асимметричными ключами из keyring
+func NewJWTServiceWithKeyring(keyring *Keyring, issuer, audience string, expiration time.Duration) *JWTService {
+	return &JWTService{
+		keyring:    keyring,
+		issuer:     issuer,
+		audience:   audience,
+		expiration: expiration,
+	}
+}
+
+// Issuer возвращает значение claim iss выдаваемых токенов
+func (j *JWTService) Issuer() string {
+	return j.issuer
+}
+
+// Audience возвращает значение claim aud выдаваемых токенов
+func (j *JWTService) Audience() string {
+	return j.audience
+}
+
+// Expiration возвращает время жизни access токена
+func (j *JWTService) Expiration() time.Duration {
+	return j.expiration
+}
+
+// Типы субъектов токена
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+// Scopes, которые проверяют маршруты самого сервиса
+const (
+	ScopeProfile = "profile"
+	// ScopeAccount дает доступ к управлению аккаунтом (пароль, email, удаление, экспорт, MFA);
+	// выдается только при входе напрямую, OAuth клиентам не выдается
+	ScopeAccount = "account"
+	// ScopeAdmin дает доступ к /admin: сервисам (client credentials) и администраторам,
+	// вошедшим напрямую; токенам пользователей от OAuth клиентов не выдается
+	ScopeAdmin = "admin"
+)
+
+// Claims содержит данные субъекта токена: пользователя (UserID, Email)
+// или сервиса, получившего токен по client credentials (ClientID, Scope).
+// Generation - поколение токенов пользователя: при смене пароля оно увеличивается,
+// и все токены с меньшим значением перестают приниматься.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID     string `json:"user_id,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Generation int    `json:"gen,omitempty"`
+	ClientID   string `json:"client_id,omitempty"`
+	Scope      string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken создает JWT токен для пользователя
-func (j *JWTService) GenerateToken(userID, email string) (string, error) {
+// Scopes возвращает список scopes токена
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope проверяет, выдан ли токену указанный scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// Principal возвращает тип субъекта токена
+func (c *Claims) Principal() string {
+	if c.UserID == "" && c.ClientID != "" {
+		return PrincipalService
+	}
+	return PrincipalUser
+}
+
+// GenerateToken создает JWT токен для пользователя с указанными scopes
+func (j *JWTService) GenerateToken(userID, email string, generation int, scopes []string) (string, error) {
 	expirationTime := time.Now().Add(j.expiration)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:     userID,
+		Email:      email,
+		Generation: generation,
+		Scope:      strings.Join(scopes, " "),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "auth-service",
+			Issuer:    j.issuer,
+			Audience:  j.audienceClaim(),
 			Subject:   userID,
+			ID:        uuid.NewString(),
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// GenerateClientToken создает JWT токен для сервиса (client credentials grant)
+func (j *JWTService) GenerateClientToken(clientID string, scopes []string) (string, error) {
+	expirationTime := time.Now().Add(j.expiration)
+
+	claims := &Claims{
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    j.issuer,
+			Audience:  j.audienceClaim(),
+			Subject:   clientID,
+			ID:        uuid.NewString(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
+}
+
+// audienceClaim возвращает claim aud; без настроенной аудитории он не выставляется
+func (j *JWTService) audienceClaim() jwt.ClaimStrings {
+	if j.audience == "" {
+		return nil
+	}
+	return jwt.ClaimStrings{j.audience}
+}
+
+// sign подписывает claims общим секретом или активным ключом keyring
+func (j *JWTService) sign(claims *Claims) (string, error) {
+	if j.keyring == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(j.secretKey)
+	}
+
+	key, err := j.keyring.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
 // ValidateToken проверяет и парсит JWT токен
 func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.secretKey, nil
-	})
+	options := []jwt.ParserOption{jwt.WithIssuer(j.issuer)}
+	if j.audience != "" {
+		// Токены, выданные для другой аудитории, не принимаются
+		options = append(options, jwt.WithAudience(j.audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.verificationKey, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -62,3 +192,27 @@ func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// verificationKey выбирает ключ проверки подписи по заголовку kid
+func (j *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if j.keyring == nil {
+		return j.secretKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	key, err := j.keyring.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Алгоритм задается ключом, а не заголовком токена
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+
+	return key.Public, nil
+}
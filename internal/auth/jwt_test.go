@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTService_GenerateClientToken(t *testing.T) {
+	jwtService := NewJWTService("secret", testIssuer, testAudience, time.Hour)
+
+	token, err := jwtService.GenerateClientToken("billing", []string{"users:read", "users:write"})
+	require.NoError(t, err)
+
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, PrincipalService, claims.Principal())
+	assert.Equal(t, "billing", claims.ClientID)
+	assert.Equal(t, "users:read users:write", claims.Scope)
+	assert.Empty(t, claims.UserID)
+
+	userToken, err := jwtService.GenerateToken("user-1", "test@example.com", 0, nil)
+	require.NoError(t, err)
+
+	userClaims, err := jwtService.ValidateToken(userToken)
+	require.NoError(t, err)
+	assert.Equal(t, PrincipalUser, userClaims.Principal())
+	assert.NotEmpty(t, userClaims.ID)
+}
+
+func TestJWTService_Audience(t *testing.T) {
+	jwtService := NewJWTService("secret", testIssuer, testAudience, time.Hour)
+
+	token, err := jwtService.GenerateToken("user-1", "test@example.com", 0, []string{"profile", "email"})
+	require.NoError(t, err)
+
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{testAudience}, []string(claims.Audience))
+	assert.True(t, claims.HasScope("profile"))
+	assert.False(t, claims.HasScope("admin"))
+
+	// Токен для другого API с тем же секретом отклоняется
+	otherService := NewJWTService("secret", testIssuer, "https://other.example.com", time.Hour)
+	_, err = otherService.ValidateToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyState - стадия жизненного цикла ключа подписи
+type KeyState string
+
+const (
+	// KeyStateNext - ключ уже опубликован и принимается, но еще не подписывает токены
+	KeyStateNext KeyState = "next"
+	// KeyStateActive - ключ, которым подписываются новые токены
+	KeyStateActive KeyState = "active"
+	// KeyStateRetired - ключ выведен из оборота и принимается только до RetiredAt
+	KeyStateRetired KeyState = "retired"
+)
+
+// Ошибки keyring
+var (
+	ErrNoActiveKey    = errors.New("no active signing key")
+	ErrNoNextKey      = errors.New("no next signing key to promote")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrKeyRetired     = errors.New("signing key is retired")
+	ErrDuplicateKey   = errors.New("duplicate signing key id")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// minRSAKeyBits - минимальный допустимый размер RSA ключа
+const minRSAKeyBits = 2048
+
+// SigningKey - асимметричный ключ подписи с идентификатором kid
+type SigningKey struct {
+	ID        string
+	State     KeyState
+	Method    jwt.SigningMethod
+	Private   crypto.Signer // nil для ключей, которые только проверяют подпись
+	Public    crypto.PublicKey
+	RetiredAt time.Time // момент, после которого retired ключ перестает приниматься
+}
+
+// Keyring хранит набор ключей подписи и управляет их ротацией (concurrent safe)
+type Keyring struct {
+	mu    sync.RWMutex
+	keys  []*SigningKey
+	grace time.Duration
+}
+
+// NewKeyring создает пустой keyring.
+// grace - сколько выведенный ключ еще принимается; должен быть не меньше времени жизни токена.
+func NewKeyring(grace time.Duration) *Keyring {
+	return &Keyring{grace: grace}
+}
+
+// Add добавляет ключ в keyring
+func (k *Keyring) Add(key *SigningKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, existing := range k.keys {
+		if existing.ID == key.ID {
+			return fmt.Errorf("%w: %s", ErrDuplicateKey, key.ID)
+		}
+		if key.State == KeyStateActive && existing.State == KeyStateActive {
+			return fmt.Errorf("key %s: keyring already has active key %s", key.ID, existing.ID)
+		}
+	}
+
+	if key.State != KeyStateRetired && key.Private == nil {
+		return fmt.Errorf("key %s: %s key requires a private key", key.ID, key.State)
+	}
+
+	added := *key
+	// Время вывода из оборота неизвестно - даем токенам дожить с момента загрузки
+	if added.State == KeyStateRetired && added.RetiredAt.IsZero() {
+		added.RetiredAt = time.Now().Add(k.grace)
+	}
+
+	k.keys = append(k.keys, &added)
+	return nil
+}
+
+// SigningKey возвращает активный ключ для подписи новых токенов
+func (k *Keyring) SigningKey() (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.State == KeyStateActive {
+			return key, nil
+		}
+	}
+	return nil, ErrNoActiveKey
+}
+
+// VerificationKey возвращает ключ по kid, если он еще принимается
+func (k *Keyring) VerificationKey(kid string) (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.isRetired(time.Now()) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyRetired, kid)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+// PublicKeys возвращает все ключи, которые еще принимаются (next, active и не истекшие retired)
+func (k *Keyring) PublicKeys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.isRetired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Rotate продвигает next ключ в active, а текущий active выводит из оборота с grace периодом,
+// чтобы уже выданные токены оставались валидными до истечения срока
+func (k *Keyring) Rotate() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	nextIdx, activeIdx := -1, -1
+	for i, key := range k.keys {
+		switch key.State {
+		case KeyStateNext:
+			if nextIdx == -1 {
+				nextIdx = i
+			}
+		case KeyStateActive:
+			activeIdx = i
+		}
+	}
+	if nextIdx == -1 {
+		return ErrNoNextKey
+	}
+
+	// Ключи заменяются копиями, чтобы не менять структуры, уже отданные вызывающим
+	promoted := *k.keys[nextIdx]
+	promoted.State = KeyStateActive
+	k.keys[nextIdx] = &promoted
+
+	if activeIdx != -1 {
+		retired := *k.keys[activeIdx]
+		retired.State = KeyStateRetired
+		retired.RetiredAt = time.Now().Add(k.grace)
+		k.keys[activeIdx] = &retired
+	}
+
+	return nil
+}
+
+// Retire немедленно выводит ключ из оборота (например, при компрометации)
+func (k *Keyring) Retire(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for i, key := range k.keys {
+		if key.ID == kid {
+			retired := *key
+			retired.State = KeyStateRetired
+			retired.RetiredAt = time.Now()
+			k.keys[i] = &retired
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+func (key *SigningKey) isRetired(now time.Time) bool {
+	return key.State == KeyStateRetired && !now.Before(key.RetiredAt)
+}
+
+// ParseKeyState разбирает стадию ключа из конфигурации
+func ParseKeyState(s string) (KeyState, error) {
+	switch state := KeyState(s); state {
+	case KeyStateNext, KeyStateActive, KeyStateRetired:
+		return state, nil
+	default:
+		return "", fmt.Errorf("unknown key state %q", s)
+	}
+}
+
+// LoadSigningKey загружает ключ из PEM файла
+func LoadSigningKey(kid string, state KeyState, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", kid, err)
+	}
+
+	key, err := ParseSigningKey(kid, state, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", kid, err)
+	}
+	return key, nil
+}
+
+// ParseSigningKey разбирает RSA, ECDSA или Ed25519 ключ из PEM.
+// Приватные ключи принимаются в PKCS#8, PKCS#1 и SEC1, публичные - в PKIX.
+func ParseSigningKey(kid string, state KeyState, pemData []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid, State: state}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	key.Method, err = signingMethodFor(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// signingMethodFor подбирает алгоритм подписи по типу публичного ключа
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("%w: ECDSA curve %s", ErrUnsupportedKey, pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "https://api.example.com"
+)
+
+// newTestKey генерирует ключ нужного типа и прогоняет его через PEM, как при загрузке из файла
+func newTestKey(t *testing.T, kid string, state KeyState, alg string) *SigningKey {
+	t.Helper()
+
+	var private any
+	var err error
+	switch alg {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	key, err := ParseSigningKey(kid, state, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWTService_Keyring_SignsWithEveryAlgorithm(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			keyring := NewKeyring(time.Hour)
+			require.NoError(t, keyring.Add(newTestKey(t, "key-1", KeyStateActive, alg)))
+			jwtService := NewJWTServiceWithKeyring(keyring, testIssuer, testAudience, time.Hour)
+
+			token, err := jwtService.GenerateToken("user-1", "test@example.com", 0, nil)
+			require.NoError(t, err)
+
+			claims, err := jwtService.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.UserID)
+		})
+	}
+}
+
+func TestJWTService_Keyring_RotationKeepsLiveTokens(t *testing.T) {
+	keyring := NewKeyring(time.Hour)
+	require.NoError(t, keyring.Add(newTestKey(t, "old", KeyStateActive, "ES256")))
+	require.NoError(t, keyring.Add(newTestKey(t, "new", KeyStateNext, "EdDSA")))
+	jwtService := NewJWTServiceWithKeyring(keyring, testIssuer, testAudience, time.Hour)
+
+	oldToken, err := jwtService.GenerateToken("user-1", "test@example.com", 0, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Rotate())
+
+	active, err := keyring.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "new", active.ID)
+
+	// Токен, подписанный старым ключом, остается валидным в grace период
+	_, err = jwtService.ValidateToken(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := jwtService.GenerateToken("user-1", "test@example.com", 0, nil)
+	require.NoError(t, err)
+	_, err = jwtService.ValidateToken(newToken)
+	assert.NoError(t, err)
+
+	// После немедленного вывода из оборота старые токены отклоняются
+	require.NoError(t, keyring.Retire("old"))
+	_, err = jwtService.ValidateToken(oldToken)
+	assert.ErrorIs(t, err, ErrKeyRetired)
+	assert.Len(t, keyring.PublicKeys(), 1)
+}
+
+func TestJWTService_Keyring_RejectsForeignTokens(t *testing.T) {
+	keyring := NewKeyring(time.Hour)
+	require.NoError(t, keyring.Add(newTestKey(t, "key-1", KeyStateActive, "RS256")))
+	jwtService := NewJWTServiceWithKeyring(keyring, testIssuer, testAudience, time.Hour)
+
+	// Токен, подписанный общим секретом, не принимается в режиме keyring
+	hsToken, err := NewJWTService("secret", testIssuer, testAudience, time.Hour).GenerateToken("user-1", "test@example.com", 0, nil)
+	require.NoError(t, err)
+	_, err = jwtService.ValidateToken(hsToken)
+	assert.Error(t, err)
+
+	// Токен с неизвестным kid отклоняется
+	otherKeyring := NewKeyring(time.Hour)
+	require.NoError(t, otherKeyring.Add(newTestKey(t, "key-2", KeyStateActive, "RS256")))
+	foreignToken, err := NewJWTServiceWithKeyring(otherKeyring, testIssuer, testAudience, time.Hour).GenerateToken("user-1", "test@example.com", 0, nil)
+	require.NoError(t, err)
+	_, err = jwtService.ValidateToken(foreignToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_AddRejectsSecondActiveKey(t *testing.T) {
+	keyring := NewKeyring(time.Hour)
+	require.NoError(t, keyring.Add(newTestKey(t, "key-1", KeyStateActive, "ES256")))
+
+	err := keyring.Add(newTestKey(t, "key-2", KeyStateActive, "ES256"))
+	assert.Error(t, err)
+}
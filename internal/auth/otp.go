@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// OTPDigits - число цифр одноразового кода из письма
+const OTPDigits = 6
+
+var otpModulus = big.NewInt(1_000_000)
+
+// NewOTPCode генерирует случайный шестизначный код (с ведущими нулями)
+func NewOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, otpModulus)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", OTPDigits, n.Int64()), nil
+}
+
+// HashOTPCode возвращает HMAC кода для хранения в БД. Кодов всего миллион, поэтому
+// простой хеш перебирается мгновенно: без секрета сервера по утекшей базе код не
+// восстановить. Назначение и subject (id пользователя) входят в подпись, поэтому
+// хеш не подходит к коду другого назначения или другого пользователя.
+func HashOTPCode(secret []byte, purpose, subject, code string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + "." + subject + "." + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckOTPCode сравнивает код с сохраненным хешем за постоянное время
+func CheckOTPCode(secret []byte, purpose, subject, code, hash string) bool {
+	expected := HashOTPCode(secret, purpose, subject, code)
+	return hmac.Equal([]byte(expected), []byte(hash))
+}
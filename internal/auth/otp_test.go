@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTPCode(t *testing.T) {
+	secret := []byte("secret")
+
+	code, err := NewOTPCode()
+	require.NoError(t, err)
+	assert.Len(t, code, OTPDigits)
+	assert.Regexp(t, `^[0-9]+$`, code)
+
+	hash := HashOTPCode(secret, "login", "user-1", code)
+	assert.True(t, CheckOTPCode(secret, "login", "user-1", code, hash))
+
+	// Код другого назначения, пользователя или с чужим секретом не подходит
+	assert.False(t, CheckOTPCode(secret, "confirm", "user-1", code, hash))
+	assert.False(t, CheckOTPCode(secret, "login", "user-2", code, hash))
+	assert.False(t, CheckOTPCode([]byte("other-secret"), "login", "user-1", code, hash))
+	assert.False(t, CheckOTPCode(secret, "login", "user-1", code+"0", hash))
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Схемы хешей паролей. Новые пароли хешируются bcrypt; остальные схемы
+// принимаются от импортированных пользователей и заменяются на bcrypt при входе.
+const (
+	HashSchemeBcrypt       = "bcrypt"
+	HashSchemeArgon2id     = "argon2id"
+	HashSchemeScrypt       = "scrypt"
+	HashSchemePBKDF2SHA256 = "pbkdf2_sha256"
+)
+
+// ErrUnsupportedPasswordHash - хеш пароля в неизвестном или поврежденном формате
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash")
+
+// Верхние границы параметров чужих хешей: хеш из импорта не должен заставлять
+// сервис тратить на проверку одного пароля гигабайты памяти или минуты CPU
+const (
+	maxArgon2Memory     = 1 << 20 // KiB
+	maxArgon2Time       = 100
+	maxScryptLogN       = 20
+	maxScryptMemory     = 1 << 30 // байт
+	maxPBKDF2Iterations = 10_000_000
+)
+
+// passwordHash - разобранный хеш пароля
+type passwordHash struct {
+	scheme string
+	verify func(password string) bool
+}
+
+// PasswordHashScheme проверяет формат хеша и возвращает его схему
+func PasswordHashScheme(encoded string) (string, error) {
+	hash, err := parsePasswordHash(encoded)
+	if err != nil {
+		return "", err
+	}
+	return hash.scheme, nil
+}
+
+// VerifyPassword сравнивает пароль с хешем любой поддерживаемой схемы.
+// Возвращает схему хеша, чтобы вызывающий мог заменить устаревший хеш на bcrypt.
+func VerifyPassword(encoded, password string) (scheme string, ok bool, err error) {
+	hash, err := parsePasswordHash(encoded)
+	if err != nil {
+		return "", false, err
+	}
+	return hash.scheme, hash.verify(password), nil
+}
+
+// parsePasswordHash определяет схему по префиксу и разбирает параметры хеша:
+//
+//	bcrypt:        $2a$10$... ($2b$, $2y$)
+//	argon2id:      $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash> (PHC, base64 без паддинга)
+//	scrypt:        $scrypt$ln=15,r=8,p=1$<salt>$<hash> (passlib, base64 без паддинга)
+//	pbkdf2_sha256: pbkdf2_sha256$<iterations>$<salt>$<hash> (Django)
+func parsePasswordHash(encoded string) (*passwordHash, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return parseBcrypt(encoded)
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return parseArgon2id(encoded)
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return parseScrypt(encoded)
+	case strings.HasPrefix(encoded, "pbkdf2_sha256$"):
+		return parsePBKDF2SHA256(encoded)
+	}
+	return nil, ErrUnsupportedPasswordHash
+}
+
+func parseBcrypt(encoded string) (*passwordHash, error) {
+	if _, err := bcrypt.Cost([]byte(encoded)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedPasswordHash, err)
+	}
+
+	return &passwordHash{
+		scheme: HashSchemeBcrypt,
+		verify: func(password string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+		},
+	}, nil
+}
+
+func parseArgon2id(encoded string) (*passwordHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[2] != "v=19" {
+		return nil, fmt.Errorf("%w: malformed argon2id hash", ErrUnsupportedPasswordHash)
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return nil, fmt.Errorf("%w: malformed argon2id parameters", ErrUnsupportedPasswordHash)
+	}
+	if memory == 0 || memory > maxArgon2Memory || time == 0 || time > maxArgon2Time || threads == 0 {
+		return nil, fmt.Errorf("%w: argon2id parameters out of range", ErrUnsupportedPasswordHash)
+	}
+
+	salt, key, err := decodeSaltAndKey(parts[4], parts[5], base64.RawStdEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passwordHash{
+		scheme: HashSchemeArgon2id,
+		verify: func(password string) bool {
+			derived := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+			return subtle.ConstantTimeCompare(derived, key) == 1
+		},
+	}, nil
+}
+
+func parseScrypt(encoded string) (*passwordHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("%w: malformed scrypt hash", ErrUnsupportedPasswordHash)
+	}
+
+	var logN, r, p int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); err != nil {
+		return nil, fmt.Errorf("%w: malformed scrypt parameters", ErrUnsupportedPasswordHash)
+	}
+	if logN < 1 || logN > maxScryptLogN || r < 1 || p < 1 || 128*r*(1<<logN) > maxScryptMemory || r*p >= 1<<30 {
+		return nil, fmt.Errorf("%w: scrypt parameters out of range", ErrUnsupportedPasswordHash)
+	}
+
+	// passlib использует "." вместо "+" в base64
+	unpasslib := strings.NewReplacer(".", "+")
+	salt, key, err := decodeSaltAndKey(unpasslib.Replace(parts[3]), unpasslib.Replace(parts[4]), base64.RawStdEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passwordHash{
+		scheme: HashSchemeScrypt,
+		verify: func(password string) bool {
+			derived, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(key))
+			return err == nil && subtle.ConstantTimeCompare(derived, key) == 1
+		},
+	}, nil
+}
+
+func parsePBKDF2SHA256(encoded string) (*passwordHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[2] == "" {
+		return nil, fmt.Errorf("%w: malformed pbkdf2_sha256 hash", ErrUnsupportedPasswordHash)
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 || iterations > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("%w: pbkdf2_sha256 iterations out of range", ErrUnsupportedPasswordHash)
+	}
+
+	// Django хранит соль как есть, а хеш - в base64 с паддингом
+	salt := []byte(parts[2])
+	key, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%w: malformed pbkdf2_sha256 hash", ErrUnsupportedPasswordHash)
+	}
+
+	return &passwordHash{
+		scheme: HashSchemePBKDF2SHA256,
+		verify: func(password string) bool {
+			derived, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+			return err == nil && subtle.ConstantTimeCompare(derived, key) == 1
+		},
+	}, nil
+}
+
+// decodeSaltAndKey декодирует соль и хеш из base64
+func decodeSaltAndKey(encodedSalt, encodedKey string, encoding *base64.Encoding) (salt, key []byte, err error) {
+	salt, err = encoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed salt", ErrUnsupportedPasswordHash)
+	}
+	key, err = encoding.DecodeString(encodedKey)
+	if err != nil || len(key) == 0 {
+		return nil, nil, fmt.Errorf("%w: malformed hash", ErrUnsupportedPasswordHash)
+	}
+	return salt, key, nil
+}
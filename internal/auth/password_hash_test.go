@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	salt := []byte("argon2-test-salt")
+	argon2Hash := fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("correct horse"), salt, 2, 1024, 1, 32)))
+
+	tests := []struct {
+		name   string
+		hash   string
+		scheme string
+	}{
+		{"bcrypt", string(bcryptHash), HashSchemeBcrypt},
+		{"argon2id", argon2Hash, HashSchemeArgon2id},
+		// Хеши получены через hashlib Python: pbkdf2_hmac и scrypt
+		{"django pbkdf2", "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso=", HashSchemePBKDF2SHA256},
+		{"passlib scrypt", "$scrypt$ln=10,r=8,p=1$cGVwcGVyLXNhbHQtMTZieQ$ZfpAlr/8dm9YZ21O01OvGbxa8bxbbVpE5uRNuBYf3PY", HashSchemeScrypt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, ok, err := VerifyPassword(tt.hash, "correct horse")
+			require.NoError(t, err)
+			assert.Equal(t, tt.scheme, scheme)
+			assert.True(t, ok)
+
+			_, ok, err = VerifyPassword(tt.hash, "battery staple")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestPasswordHashScheme_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"plain md5", "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{"truncated bcrypt", "$2a$10$abc"},
+		{"argon2i", "$argon2i$v=19$m=1024,t=2,p=1$c2FsdA$aGFzaA"},
+		{"argon2id old version", "$argon2id$v=16$m=1024,t=2,p=1$c2FsdA$aGFzaA"},
+		{"argon2id huge memory", "$argon2id$v=19$m=4194304,t=2,p=1$c2FsdA$aGFzaA"},
+		{"scrypt huge cost", "$scrypt$ln=30,r=8,p=1$c2FsdA$aGFzaA"},
+		{"pbkdf2 bad iterations", "pbkdf2_sha256$abc$salt$aGFzaA=="},
+		{"pbkdf2 sha1", "pbkdf2_sha1$1000$salt$aGFzaA=="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PasswordHashScheme(tt.hash)
+			assert.ErrorIs(t, err, ErrUnsupportedPasswordHash)
+		})
+	}
+}
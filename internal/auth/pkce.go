@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+// PKCEMethodS256 - единственный поддерживаемый метод PKCE (RFC 7636)
+const PKCEMethodS256 = "S256"
+
+// codeVerifierPattern - 43-128 символов из unreserved набора RFC 7636
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// ValidCodeChallenge проверяет формат code_challenge для метода S256
+// (base64url от SHA-256 без паддинга - ровно 43 символа)
+func ValidCodeChallenge(challenge string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// VerifyPKCE сверяет code_verifier с сохраненным code_challenge
+func VerifyPKCE(verifier, challenge, method string) bool {
+	if method != PKCEMethodS256 || !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
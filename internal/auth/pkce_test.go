@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.True(t, ValidCodeChallenge(challenge))
+	assert.True(t, VerifyPKCE(verifier, challenge, PKCEMethodS256))
+	assert.False(t, VerifyPKCE(verifier, challenge, "plain"))
+	assert.False(t, VerifyPKCE(verifier+"x", challenge, PKCEMethodS256))
+	assert.False(t, VerifyPKCE("short", challenge, PKCEMethodS256))
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrSecretBoxOpen - шифротекст поврежден, подменен или зашифрован другим ключом
+var ErrSecretBoxOpen = errors.New("failed to decrypt secret")
+
+// SecretBox шифрует секреты для хранения в БД (AES-256-GCM). Ключ выводится
+// из строки конфигурации через SHA-256. Шифротекст привязан к контексту
+// (например, id пользователя): скопированный в чужую строку, он не расшифруется.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox создает шифратор с ключом, выведенным из key
+func NewSecretBox(key string) (*SecretBox, error) {
+	sum := sha256.Sum256([]byte(key))
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal шифрует plaintext и возвращает base64(nonce || шифротекст)
+func (b *SecretBox) Seal(plaintext, context string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает результат Seal с тем же контекстом
+func (b *SecretBox) Open(sealed, context string) (string, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < b.aead.NonceSize() {
+		return "", ErrSecretBoxOpen
+	}
+
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", ErrSecretBoxOpen
+	}
+
+	return string(plaintext), nil
+}
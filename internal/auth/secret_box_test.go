@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretBox(t *testing.T) {
+	box, err := NewSecretBox("key")
+	require.NoError(t, err)
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP", "user-1")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+	plaintext, err := box.Open(sealed, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	// Чужой контекст, другой ключ или измененный шифротекст не расшифровываются
+	_, err = box.Open(sealed, "user-2")
+	assert.ErrorIs(t, err, ErrSecretBoxOpen)
+
+	otherBox, err := NewSecretBox("other-key")
+	require.NoError(t, err)
+	_, err = otherBox.Open(sealed, "user-1")
+	assert.ErrorIs(t, err, ErrSecretBoxOpen)
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+	_, err = box.Open(string(tampered), "user-1")
+	assert.ErrorIs(t, err, ErrSecretBoxOpen)
+
+	_, err = box.Open("", "user-1")
+	assert.ErrorIs(t, err, ErrSecretBoxOpen)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSignedToken - токен поврежден, подделан или выдан для другого назначения
+var ErrInvalidSignedToken = errors.New("invalid signed token")
+
+// NewSignedToken генерирует одноразовый токен для ссылок в письмах: случайная часть
+// подписывается HMAC вместе с назначением (purpose), поэтому подделанный токен или
+// токен другого назначения отклоняется без обращения к БД.
+// Возвращает токен для пользователя и хеш для хранения в БД.
+func NewSignedToken(secret []byte, purpose string) (token, hash string, err error) {
+	payload, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	token = payload + "." + signTokenPayload(secret, purpose, payload)
+	return token, HashOpaqueToken(token), nil
+}
+
+// VerifySignedToken проверяет подпись токена и возвращает его хеш для поиска в БД
+func VerifySignedToken(secret []byte, purpose, token string) (string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || payload == "" {
+		return "", ErrInvalidSignedToken
+	}
+
+	expected := signTokenPayload(secret, purpose, payload)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", ErrInvalidSignedToken
+	}
+
+	return HashOpaqueToken(token), nil
+}
+
+func signTokenPayload(secret []byte, purpose, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
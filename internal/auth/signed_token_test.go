@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedToken(t *testing.T) {
+	secret := []byte("secret")
+
+	token, hash, err := NewSignedToken(secret, "verify_email")
+	require.NoError(t, err)
+
+	verifiedHash, err := VerifySignedToken(secret, "verify_email", token)
+	require.NoError(t, err)
+	assert.Equal(t, hash, verifiedHash)
+
+	// Токен другого назначения, с чужим секретом или измененный не принимается
+	_, err = VerifySignedToken(secret, "reset_password", token)
+	assert.ErrorIs(t, err, ErrInvalidSignedToken)
+
+	_, err = VerifySignedToken([]byte("other-secret"), "verify_email", token)
+	assert.ErrorIs(t, err, ErrInvalidSignedToken)
+
+	_, err = VerifySignedToken(secret, "verify_email", "x"+token)
+	assert.ErrorIs(t, err, ErrInvalidSignedToken)
+
+	_, err = VerifySignedToken(secret, "verify_email", "no-signature")
+	assert.ErrorIs(t, err, ErrInvalidSignedToken)
+}
@@ -0,0 +1,340 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Формат архива пользователей. Version увеличивается при изменениях записи;
+// архивы прежних версий читаются, недостающие колонки получают значения по умолчанию.
+// Версия 2 добавила колонки TOTP.
+const (
+	Format  = "auth-service/users"
+	Version = 2
+)
+
+// Ошибки чтения архива
+var (
+	ErrMalformedArchive   = errors.New("malformed user archive")
+	ErrUnsupportedVersion = errors.New("unsupported user archive version")
+	// ErrChecksumMismatch - архив поврежден или изменен после выгрузки
+	ErrChecksumMismatch = errors.New("user archive checksum mismatch")
+	// ErrTruncatedArchive - в архиве нет завершающей строки, выгрузка оборвалась
+	ErrTruncatedArchive = errors.New("user archive is truncated")
+)
+
+// Header - первая строка архива
+type Header struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Trailer - последняя строка архива: число пользователей и SHA-256 всех строк до нее
+type Trailer struct {
+	Users  int    `json:"users"`
+	SHA256 string `json:"sha256"`
+}
+
+// User - пользователь в архиве со всеми колонками, включая хеш пароля
+// и секрет TOTP (в архиве он остается зашифрованным ключом сервера)
+type User struct {
+	ID                    uuid.UUID         `json:"id"`
+	Email                 string            `json:"email"`
+	PasswordHash          string            `json:"password_hash"`
+	EmailVerifiedAt       *time.Time        `json:"email_verified_at"`
+	TokenGeneration       int               `json:"token_generation"`
+	DeletedAt             *time.Time        `json:"deleted_at"`
+	PurgeAfter            *time.Time        `json:"purge_after"`
+	Status                models.UserStatus `json:"status"`
+	StatusReason          *string           `json:"status_reason"`
+	StatusChangedAt       time.Time         `json:"status_changed_at"`
+	Role                  models.UserRole   `json:"role"`
+	PasswordResetRequired bool              `json:"password_reset_required"`
+	FailedLoginAttempts   int               `json:"failed_login_attempts"`
+	LockoutCount          int               `json:"lockout_count"`
+	LockedUntil           *time.Time        `json:"locked_until"`
+	TOTPSecret            string            `json:"totp_secret,omitempty"`
+	TOTPEnabledAt         *time.Time        `json:"totp_enabled_at,omitempty"`
+	TOTPLastUsedStep      int64             `json:"totp_last_used_step,omitempty"`
+	Username              *string           `json:"username"`
+	DisplayName           *string           `json:"display_name"`
+	AvatarURL             *string           `json:"avatar_url"`
+	Locale                *string           `json:"locale"`
+	Timezone              *string           `json:"timezone"`
+	Metadata              map[string]any    `json:"metadata"`
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+}
+
+// line - строка архива; заполнено ровно одно поле
+type line struct {
+	Header  *Header  `json:"header,omitempty"`
+	User    *User    `json:"user,omitempty"`
+	Trailer *Trailer `json:"trailer,omitempty"`
+}
+
+// NewUser переводит пользователя в запись архива; время приводится к UTC
+func NewUser(user *models.User) *User {
+	return &User{
+		ID:                    user.ID,
+		Email:                 user.Email,
+		PasswordHash:          user.PasswordHash,
+		EmailVerifiedAt:       utc(user.EmailVerifiedAt),
+		TokenGeneration:       user.TokenGeneration,
+		DeletedAt:             utc(user.DeletedAt),
+		PurgeAfter:            utc(user.PurgeAfter),
+		Status:                user.Status,
+		StatusReason:          user.StatusReason,
+		StatusChangedAt:       user.StatusChangedAt.UTC(),
+		Role:                  user.Role,
+		PasswordResetRequired: user.PasswordResetRequired,
+		FailedLoginAttempts:   user.FailedLoginAttempts,
+		LockoutCount:          user.LockoutCount,
+		LockedUntil:           utc(user.LockedUntil),
+		TOTPSecret:            user.TOTPSecret,
+		TOTPEnabledAt:         utc(user.TOTPEnabledAt),
+		TOTPLastUsedStep:      user.TOTPLastUsedStep,
+		Username:              user.Username,
+		DisplayName:           user.DisplayName,
+		AvatarURL:             user.AvatarURL,
+		Locale:                user.Locale,
+		Timezone:              user.Timezone,
+		Metadata:              user.Metadata,
+		CreatedAt:             user.CreatedAt.UTC(),
+		UpdatedAt:             user.UpdatedAt.UTC(),
+	}
+}
+
+// Model переводит запись архива в пользователя
+func (u *User) Model() *models.User {
+	return &models.User{
+		ID:                    u.ID,
+		Email:                 u.Email,
+		PasswordHash:          u.PasswordHash,
+		EmailVerifiedAt:       u.EmailVerifiedAt,
+		TokenGeneration:       u.TokenGeneration,
+		DeletedAt:             u.DeletedAt,
+		PurgeAfter:            u.PurgeAfter,
+		Status:                u.Status,
+		StatusReason:          u.StatusReason,
+		StatusChangedAt:       u.StatusChangedAt,
+		Role:                  u.Role,
+		PasswordResetRequired: u.PasswordResetRequired,
+		FailedLoginAttempts:   u.FailedLoginAttempts,
+		LockoutCount:          u.LockoutCount,
+		LockedUntil:           u.LockedUntil,
+		TOTPSecret:            u.TOTPSecret,
+		TOTPEnabledAt:         u.TOTPEnabledAt,
+		TOTPLastUsedStep:      u.TOTPLastUsedStep,
+		Profile: models.Profile{
+			Username:    u.Username,
+			DisplayName: u.DisplayName,
+			AvatarURL:   u.AvatarURL,
+			Locale:      u.Locale,
+			Timezone:    u.Timezone,
+			Metadata:    u.Metadata,
+		},
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// utc приводит необязательное время к UTC
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	converted := t.UTC()
+	return &converted
+}
+
+// Equal сообщает, совпадают ли пользователи во всех колонках архива
+func Equal(a, b *models.User) bool {
+	encodedA, errA := json.Marshal(NewUser(a))
+	encodedB, errB := json.Marshal(NewUser(b))
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// Writer пишет архив пользователей: строка заголовка, по строке на пользователя
+// и завершающая строка с числом пользователей и контрольной суммой
+type Writer struct {
+	w     *bufio.Writer
+	sum   hash.Hash
+	users int
+}
+
+// NewWriter начинает архив и пишет заголовок
+func NewWriter(w io.Writer, exportedAt time.Time) (*Writer, error) {
+	writer := &Writer{
+		w:   bufio.NewWriter(w),
+		sum: sha256.New(),
+	}
+
+	header := &Header{Format: Format, Version: Version, ExportedAt: exportedAt.UTC()}
+	if err := writer.writeLine(&line{Header: header}, true); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write добавляет пользователя в архив
+func (w *Writer) Write(user *models.User) error {
+	if err := w.writeLine(&line{User: NewUser(user)}, true); err != nil {
+		return err
+	}
+	w.users++
+	return nil
+}
+
+// Close пишет завершающую строку и сбрасывает буфер. Нижележащий writer не закрывается.
+func (w *Writer) Close() error {
+	trailer := &Trailer{Users: w.users, SHA256: hex.EncodeToString(w.sum.Sum(nil))}
+	if err := w.writeLine(&line{Trailer: trailer}, false); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Users возвращает число записанных пользователей
+func (w *Writer) Users() int {
+	return w.users
+}
+
+func (w *Writer) writeLine(l *line, checksummed bool) error {
+	encoded, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive line: %w", err)
+	}
+	encoded = append(encoded, '\n')
+
+	if checksummed {
+		w.sum.Write(encoded)
+	}
+	if _, err := w.w.Write(encoded); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Reader читает архив пользователей. Контрольная сумма проверяется по завершающей
+// строке, поэтому до конца архива прочитанным пользователям доверять нельзя:
+// перед восстановлением архив нужно проверить целиком (Verify).
+type Reader struct {
+	r      *bufio.Reader
+	sum    hash.Hash
+	header *Header
+	users  int
+	line   int
+	done   bool
+}
+
+// NewReader читает и проверяет заголовок архива
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{
+		r:   bufio.NewReader(r),
+		sum: sha256.New(),
+	}
+
+	l, err := reader.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if l.Header == nil || l.Header.Format != Format {
+		return nil, fmt.Errorf("%w: line 1 is not an archive header", ErrMalformedArchive)
+	}
+	if l.Header.Version < 1 || l.Header.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, l.Header.Version)
+	}
+
+	reader.header = l.Header
+	return reader, nil
+}
+
+// Header возвращает заголовок архива
+func (r *Reader) Header() *Header {
+	return r.header
+}
+
+// Next возвращает следующего пользователя. В конце архива проверяет число пользователей
+// и контрольную сумму и возвращает io.EOF, если они совпали.
+func (r *Reader) Next() (*models.User, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	l, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case l.User != nil:
+		r.users++
+		return l.User.Model(), nil
+	case l.Trailer != nil:
+		r.done = true
+		if l.Trailer.Users != r.users || l.Trailer.SHA256 != hex.EncodeToString(r.sum.Sum(nil)) {
+			return nil, ErrChecksumMismatch
+		}
+		// После завершающей строки данных быть не должно
+		if _, err := r.r.Peek(1); err != io.EOF {
+			return nil, fmt.Errorf("%w: data after trailer", ErrMalformedArchive)
+		}
+		return nil, io.EOF
+	}
+	return nil, fmt.Errorf("%w: unexpected line %d", ErrMalformedArchive, r.line)
+}
+
+// readLine читает строку и добавляет ее в контрольную сумму, если это не завершающая строка
+func (r *Reader) readLine() (*line, error) {
+	raw, err := r.r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		if len(raw) == 0 {
+			return nil, ErrTruncatedArchive
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	r.line++
+
+	var l line
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedArchive, r.line, err)
+	}
+	if l.Trailer == nil {
+		r.sum.Write(raw)
+	}
+	return &l, nil
+}
+
+// Verify читает архив целиком и проверяет заголовок, число пользователей и контрольную сумму.
+// Возвращает заголовок и число пользователей.
+func Verify(r io.Reader) (*Header, int, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return reader.header, reader.users, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newArchiveUser(email string) *models.User {
+	now := time.Now().Truncate(time.Microsecond)
+	username := strings.Split(email, "@")[0]
+	return &models.User{
+		ID:               uuid.New(),
+		Email:            email,
+		PasswordHash:     "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		EmailVerifiedAt:  &now,
+		TokenGeneration:  3,
+		Status:           models.UserStatusActive,
+		StatusChangedAt:  now,
+		Role:             models.RoleAdmin,
+		TOTPSecret:       "c2VhbGVkLXRvdHAtc2VjcmV0",
+		TOTPEnabledAt:    &now,
+		TOTPLastUsedStep: 58000000,
+		Profile: models.Profile{
+			Username: &username,
+			Metadata: map[string]any{"plan": "pro", "seats": float64(5)},
+		},
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now,
+	}
+}
+
+func writeArchive(t *testing.T, users ...*models.User) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, time.Now())
+	require.NoError(t, err)
+	for _, user := range users {
+		require.NoError(t, writer.Write(user))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	// Arrange
+	users := []*models.User{newArchiveUser("alice@example.com"), newArchiveUser("bob@example.com")}
+	archive := writeArchive(t, users...)
+
+	// Act
+	header, count, err := Verify(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	reader, err := NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	var restored []*models.User
+	for {
+		user, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		restored = append(restored, user)
+	}
+
+	// Assert
+	assert.Equal(t, Version, header.Version)
+	assert.Equal(t, 2, count)
+	require.Len(t, restored, 2)
+	for i := range users {
+		assert.True(t, Equal(users[i], restored[i]), "user %d changed after round trip", i)
+		assert.Equal(t, users[i].PasswordHash, restored[i].PasswordHash)
+	}
+}
+
+func TestArchive_Rejected(t *testing.T) {
+	archive := string(writeArchive(t, newArchiveUser("alice@example.com")))
+	lines := strings.SplitAfter(archive, "\n")
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr error
+	}{
+		{"tampered user", strings.Replace(archive, "alice@", "mallory@", 1), ErrChecksumMismatch},
+		{"missing user", lines[0] + lines[2], ErrChecksumMismatch},
+		{"truncated", lines[0] + lines[1], ErrTruncatedArchive},
+		{"data after trailer", archive + lines[1], ErrMalformedArchive},
+		{"not an archive", `{"email":"alice@example.com"}` + "\n", ErrMalformedArchive},
+		{"future version", strings.Replace(archive, fmt.Sprintf(`"version":%d`, Version), fmt.Sprintf(`"version":%d`, Version+1), 1), ErrUnsupportedVersion},
+		{"empty", "", ErrTruncatedArchive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Verify(strings.NewReader(tt.archive))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestArchive_ReadsVersion1(t *testing.T) {
+	// Arrange: архив первой версии, до появления колонок TOTP
+	user := newArchiveUser("alice@example.com")
+	user.TOTPSecret, user.TOTPEnabledAt, user.TOTPLastUsedStep = "", nil, 0
+
+	var buf bytes.Buffer
+	writer := &Writer{w: bufio.NewWriter(&buf), sum: sha256.New()}
+	require.NoError(t, writer.writeLine(&line{Header: &Header{Format: Format, Version: 1, ExportedAt: time.Now()}}, true))
+	require.NoError(t, writer.Write(user))
+	require.NoError(t, writer.Close())
+
+	// Act
+	header, count, err := Verify(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	reader, err := NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	restored, err := reader.Next()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, header.Version)
+	assert.Equal(t, 1, count)
+	assert.True(t, Equal(user, restored))
+	assert.False(t, restored.TOTPEnabled())
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// RevokedTokenCache хранит отозванные jti в памяти до истечения исходного токена
+type RevokedTokenCache struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+}
+
+func NewRevokedTokenCache() *RevokedTokenCache {
+	return &RevokedTokenCache{
+		tokens: make(map[string]time.Time),
+	}
+}
+
+// Add помечает jti отозванным (concurrent safe)
+func (c *RevokedTokenCache) Add(jti string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens[jti] = expiresAt
+}
+
+// Contains проверяет, отозван ли jti (concurrent safe)
+func (c *RevokedTokenCache) Contains(jti string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.tokens[jti]
+	return exists
+}
+
+// Prune удаляет записи о токенах, истекших к моменту now (concurrent safe)
+func (c *RevokedTokenCache) Prune(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pruned := 0
+	for jti, expiresAt := range c.tokens {
+		if now.After(expiresAt) {
+			delete(c.tokens, jti)
+			pruned++
+		}
+	}
+	return pruned
+}
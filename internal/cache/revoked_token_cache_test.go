@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedTokenCache_Prune(t *testing.T) {
+	cache := NewRevokedTokenCache()
+
+	cache.Add("expired", time.Now().Add(-time.Minute))
+	cache.Add("live", time.Now().Add(time.Hour))
+	assert.True(t, cache.Contains("expired"))
+
+	// Истекшие токены удаляются, живые остаются
+	assert.Equal(t, 1, cache.Prune(time.Now()))
+	assert.False(t, cache.Contains("expired"))
+	assert.True(t, cache.Contains("live"))
+}
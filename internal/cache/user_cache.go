@@ -2,6 +2,7 @@ package cache
 
 import (
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,16 +14,19 @@ type CacheItem struct {
 	ExpiresAt time.Time
 }
 
+// UserCache хранит пользователей по email; usernames - индекс username (в нижнем регистре) -> email
 type UserCache struct {
-	mu    sync.RWMutex
-	users map[string]*CacheItem
-	ttl   time.Duration
+	mu        sync.RWMutex
+	users     map[string]*CacheItem
+	usernames map[string]string
+	ttl       time.Duration
 }
 
 func NewUserCache(ttl time.Duration) *UserCache {
 	return &UserCache{
-		users: make(map[string]*CacheItem),
-		ttl:   ttl,
+		users:     make(map[string]*CacheItem),
+		usernames: make(map[string]string),
+		ttl:       ttl,
 	}
 }
 
@@ -44,15 +48,38 @@ func (c *UserCache) Get(email string) *models.User {
 	return item.User
 }
 
+// GetByUsername возвращает пользователя из кеша по username без учета регистра (concurrent safe)
+func (c *UserCache) GetByUsername(username string) *models.User {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	email, exists := c.usernames[strings.ToLower(username)]
+	if !exists {
+		return nil
+	}
+
+	item, exists := c.users[email]
+	if !exists || time.Now().After(item.ExpiresAt) {
+		return nil
+	}
+
+	log.Printf("✅ Cache HIT for username: %s", username)
+	return item.User
+}
+
 // Set сохраняет пользователя в кеш (concurrent safe)
 func (c *UserCache) Set(email string, user *models.User) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.deleteUsername(email)
 	c.users[email] = &CacheItem{
 		User:      user,
 		ExpiresAt: time.Now().Add(c.ttl),
 	}
+	if user.Username != nil {
+		c.usernames[strings.ToLower(*user.Username)] = email
+	}
 	log.Printf("💾 Cache SET for email: %s", email)
 }
 
@@ -60,6 +87,21 @@ func (c *UserCache) Set(email string, user *models.User) {
 func (c *UserCache) Delete(email string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.deleteUsername(email)
 	delete(c.users, email)
 	log.Printf("🗑️ Cache DELETE for email: %s", email)
 }
+
+// deleteUsername удаляет из индекса username пользователя, закешированного по email.
+// Вызывается под блокировкой.
+func (c *UserCache) deleteUsername(email string) {
+	item, exists := c.users[email]
+	if !exists || item.User.Username == nil {
+		return
+	}
+
+	key := strings.ToLower(*item.User.Username)
+	if c.usernames[key] == email {
+		delete(c.usernames, key)
+	}
+}
@@ -76,3 +76,27 @@ func TestUserCache_Delete(t *testing.T) {
 	cache.Delete(user.Email)
 	assert.Nil(t, cache.Get(user.Email), "User should be deleted from cache")
 }
+
+func TestUserCache_GetByUsername(t *testing.T) {
+	cache := NewUserCache(5 * time.Minute)
+	username := "Alice"
+	user := &models.User{
+		ID:    uuid.New(),
+		Email: "alice@example.com",
+	}
+	user.Username = &username
+
+	cache.Set(user.Email, user)
+	assert.Equal(t, user, cache.GetByUsername("alice"), "Username lookup should ignore case")
+
+	// После смены username старое имя не должно находить пользователя
+	renamed := *user
+	newUsername := "alice2"
+	renamed.Username = &newUsername
+	cache.Set(renamed.Email, &renamed)
+	assert.Nil(t, cache.GetByUsername("alice"), "Old username should be dropped")
+	assert.NotNil(t, cache.GetByUsername("ALICE2"))
+
+	cache.Delete(renamed.Email)
+	assert.Nil(t, cache.GetByUsername("alice2"), "Username should be deleted with user")
+}
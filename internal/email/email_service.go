@@ -6,6 +6,7 @@ import (
 	"net/smtp"
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -73,6 +74,229 @@ func (s *EmailService) SendWelcomeEmail(email, userName string) error {
 	return nil
 }
 
+// SendVerificationEmail отправляет ссылку для подтверждения адреса
+func (s *EmailService) SendVerificationEmail(email, link string) error {
+	subject := "Подтвердите адрес электронной почты"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Чтобы подтвердить адрес электронной почты, перейдите по ссылке:
+
+%s
+
+Если вы не регистрировались в нашем приложении, просто проигнорируйте это письмо.
+
+С уважением,
+Команда Auth Servise
+`, link)
+
+	log.Printf("📧 Starting to send verification email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	log.Printf("✅ Verification email sent successfully to: %s", email)
+	return nil
+}
+
+// SendPasswordResetEmail отправляет ссылку для сброса пароля
+func (s *EmailService) SendPasswordResetEmail(email, link string) error {
+	subject := "Сброс пароля"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Мы получили запрос на сброс пароля для вашего аккаунта.
+Чтобы задать новый пароль, перейдите по ссылке:
+
+%s
+
+Ссылка одноразовая и скоро перестанет действовать.
+Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.
+
+С уважением,
+Команда Auth Servise
+`, link)
+
+	log.Printf("📧 Starting to send password reset email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	log.Printf("✅ Password reset email sent successfully to: %s", email)
+	return nil
+}
+
+// SendEmailChangeConfirmation отправляет на новый адрес ссылку для подтверждения смены email
+func (s *EmailService) SendEmailChangeConfirmation(email, link string) error {
+	subject := "Подтвердите новый адрес электронной почты"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Вы запросили смену адреса электронной почты на этот адрес.
+Чтобы подтвердить смену, перейдите по ссылке:
+
+%s
+
+Если вы не запрашивали смену адреса, просто проигнорируйте это письмо.
+
+С уважением,
+Команда Auth Servise
+`, link)
+
+	log.Printf("📧 Starting to send email change confirmation to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send email change confirmation: %w", err)
+	}
+
+	log.Printf("✅ Email change confirmation sent successfully to: %s", email)
+	return nil
+}
+
+// SendEmailChangeNotice уведомляет прежний адрес о запросе смены email и дает ссылку для отмены
+func (s *EmailService) SendEmailChangeNotice(email, newEmail, undoLink string) error {
+	subject := "Запрошена смена адреса электронной почты"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Для вашего аккаунта запрошена смена адреса электронной почты на %s.
+
+Если это были не вы, отмените смену по ссылке - она также вернет прежний адрес,
+если смена уже подтверждена, и завершит все активные сессии:
+
+%s
+
+С уважением,
+Команда Auth Servise
+`, newEmail, undoLink)
+
+	log.Printf("📧 Starting to send email change notice to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send email change notice: %w", err)
+	}
+
+	log.Printf("✅ Email change notice sent successfully to: %s", email)
+	return nil
+}
+
+// SendDataExportEmail отправляет ссылку для скачивания выгрузки данных аккаунта
+func (s *EmailService) SendDataExportEmail(email, link string) error {
+	subject := "Выгрузка данных аккаунта"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Выгрузка данных вашего аккаунта готова. Скачать ее можно по ссылке:
+
+%s
+
+Ссылка одноразовая и скоро перестанет действовать.
+Если вы не запрашивали выгрузку, смените пароль.
+
+С уважением,
+Команда Auth Servise
+`, link)
+
+	log.Printf("📧 Starting to send data export email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send data export email: %w", err)
+	}
+
+	log.Printf("✅ Data export email sent successfully to: %s", email)
+	return nil
+}
+
+// SendMagicLinkEmail отправляет одноразовую ссылку для входа без пароля
+func (s *EmailService) SendMagicLinkEmail(email, link string) error {
+	subject := "Вход в аккаунт"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Чтобы войти в аккаунт, перейдите по ссылке:
+
+%s
+
+Ссылка одноразовая, скоро перестанет действовать и откроется только
+на устройстве, с которого был запрошен вход.
+Если вы не запрашивали вход, просто проигнорируйте это письмо.
+
+С уважением,
+Команда Auth Servise
+`, link)
+
+	log.Printf("📧 Starting to send magic link email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send magic link email: %w", err)
+	}
+
+	log.Printf("✅ Magic link email sent successfully to: %s", email)
+	return nil
+}
+
+// SendOTPEmail отправляет одноразовый код для входа или подтверждения действия
+func (s *EmailService) SendOTPEmail(email, code string, ttl time.Duration) error {
+	subject := "Ваш код подтверждения"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Ваш одноразовый код: %s
+
+Код действует %d мин. Никому не сообщайте его: сотрудники сервиса
+никогда не спрашивают коды.
+Если вы не запрашивали код, просто проигнорируйте это письмо.
+
+С уважением,
+Команда Auth Servise
+`, code, int(ttl.Minutes()))
+
+	log.Printf("📧 Starting to send OTP email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send OTP email: %w", err)
+	}
+
+	log.Printf("✅ OTP email sent successfully to: %s", email)
+	return nil
+}
+
+// SendAccountLockedEmail уведомляет о блокировке входа после серии неудачных попыток
+func (s *EmailService) SendAccountLockedEmail(email string, lockedUntil time.Time) error {
+	subject := "Вход в аккаунт временно заблокирован"
+
+	body := fmt.Sprintf(`
+Здравствуйте!
+
+Мы заметили несколько неудачных попыток входа в ваш аккаунт подряд
+и временно заблокировали вход до %s (UTC).
+
+Если это были вы, просто попробуйте войти позже или восстановите пароль.
+Если нет - кто-то пытается подобрать пароль: рекомендуем сменить его на более надежный.
+
+С уважением,
+Команда Auth Servise
+`, lockedUntil.UTC().Format("02.01.2006 15:04"))
+
+	log.Printf("📧 Starting to send account locked email to: %s", email)
+
+	if err := s.sendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("failed to send account locked email: %w", err)
+	}
+
+	log.Printf("✅ Account locked email sent successfully to: %s", email)
+	return nil
+}
+
 // sendEmail отправляет email через SMTP
 func (s *EmailService) sendEmail(to, subject, body string) error {
 	// Формируем сообщение
@@ -109,3 +333,64 @@ func (s *EmailService) SendWelcomeEmailAsync(email, userName string) {
         }
     }()
 }
+
+// SendVerificationEmailAsync запускает отправку письма с подтверждением в фоне
+func (s *EmailService) SendVerificationEmailAsync(email, link string) {
+	s.sendAsync(func() error {
+		return s.SendVerificationEmail(email, link)
+	})
+}
+
+// SendPasswordResetEmailAsync запускает отправку письма для сброса пароля в фоне
+func (s *EmailService) SendPasswordResetEmailAsync(email, link string) {
+	s.sendAsync(func() error {
+		return s.SendPasswordResetEmail(email, link)
+	})
+}
+
+// SendEmailChangeConfirmationAsync запускает отправку подтверждения смены email в фоне
+func (s *EmailService) SendEmailChangeConfirmationAsync(email, link string) {
+	s.sendAsync(func() error {
+		return s.SendEmailChangeConfirmation(email, link)
+	})
+}
+
+// SendEmailChangeNoticeAsync запускает отправку уведомления о смене email в фоне
+func (s *EmailService) SendEmailChangeNoticeAsync(email, newEmail, undoLink string) {
+	s.sendAsync(func() error {
+		return s.SendEmailChangeNotice(email, newEmail, undoLink)
+	})
+}
+
+// SendMagicLinkEmailAsync запускает отправку ссылки для входа в фоне
+func (s *EmailService) SendMagicLinkEmailAsync(email, link string) {
+	s.sendAsync(func() error {
+		return s.SendMagicLinkEmail(email, link)
+	})
+}
+
+// SendOTPEmailAsync запускает отправку одноразового кода в фоне
+func (s *EmailService) SendOTPEmailAsync(email, code string, ttl time.Duration) {
+	s.sendAsync(func() error {
+		return s.SendOTPEmail(email, code, ttl)
+	})
+}
+
+// SendAccountLockedEmailAsync запускает отправку уведомления о блокировке входа в фоне
+func (s *EmailService) SendAccountLockedEmailAsync(email string, lockedUntil time.Time) {
+	s.sendAsync(func() error {
+		return s.SendAccountLockedEmail(email, lockedUntil)
+	})
+}
+
+// sendAsync выполняет отправку в фоне, ограничивая число одновременных отправок пулом
+func (s *EmailService) sendAsync(send func() error) {
+	go func() {
+		s.workerPool.Acquire()
+		defer s.workerPool.Release()
+
+		if err := send(); err != nil {
+			log.Printf("❌ %v", err)
+		}
+	}()
+}
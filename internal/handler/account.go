@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountService интерфейс удаления и восстановления аккаунта
+type AccountService interface {
+	DeleteAccount(ctx context.Context, userID, password, code string) (time.Time, error)
+	RestoreAccount(ctx context.Context, email, password string) (*service.AuthResponse, error)
+}
+
+type AccountHandler struct {
+	accountService AccountService
+}
+
+func NewAccountHandler(accountService AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя (требуется пароль или, без пароля, код из письма)
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	purgeAfter, err := h.accountService.DeleteAccount(c.Request.Context(), userID.(string), req.Password, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountLocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Account is temporarily locked",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrWrongPassword) || errors.Is(err, service.ErrInvalidOTP) || errors.Is(err, service.ErrAccountDeleted) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to delete account",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete account",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Account scheduled for deletion",
+		"purge_after": purgeAfter,
+	})
+}
+
+// RestoreAccount отменяет удаление аккаунта и выполняет вход
+func (h *AccountHandler) RestoreAccount(c *gin.Context) {
+	var req models.RestoreAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.accountService.RestoreAccount(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Authentication failed",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrAccountLocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Account is temporarily locked",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Account is suspended",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to restore account",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withTokens(gin.H{
+		"message": "Account restored successfully",
+		"user": gin.H{
+			"id":    authResponse.User.ID,
+			"email": authResponse.User.Email,
+		},
+	}, authResponse))
+}
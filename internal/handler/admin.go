@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminService интерфейс операций администратора над пользователями
+type AdminService interface {
+	ListUsers(ctx context.Context, req *models.ListUsersRequest) (*service.UserPage, error)
+	GetUser(ctx context.Context, userID string) (*service.UserDetails, error)
+	SuspendUser(ctx context.Context, userID, reason string) (*models.User, error)
+	UnsuspendUser(ctx context.Context, userID, reason string) (*models.User, error)
+	ForceLogout(ctx context.Context, userID string) error
+	ForcePasswordReset(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, userID, reason string) (time.Time, error)
+	UnlockAccount(ctx context.Context, userID string) error
+}
+
+type AdminHandler struct {
+	adminService AdminService
+}
+
+func NewAdminHandler(adminService AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+	}
+}
+
+// ListUsers возвращает страницу пользователей с фильтрами по статусу,
+// дате регистрации и префиксу email
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var req models.ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	page, err := h.adminService.ListUsers(c.Request.Context(), &req)
+	if err != nil {
+		adminErrorResponse(c, err, "Failed to list users")
+		return
+	}
+
+	users := make([]gin.H, len(page.Users))
+	for i := range page.Users {
+		users[i] = adminUserResponse(&page.Users[i])
+	}
+
+	body := gin.H{"users": users}
+	if page.NextCursor != "" {
+		body["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// GetUser возвращает пользователя с историей статусов
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	details, err := h.adminService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		adminErrorResponse(c, err, "Failed to get user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":           adminUserResponse(details.User),
+		"status_history": details.StatusHistory,
+	})
+}
+
+// SuspendUser приостанавливает аккаунт
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	h.changeStatus(c, h.adminService.SuspendUser, "User suspended successfully", "Failed to suspend user")
+}
+
+// UnsuspendUser снимает приостановку аккаунта
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	h.changeStatus(c, h.adminService.UnsuspendUser, "User unsuspended successfully", "Failed to unsuspend user")
+}
+
+// ForceLogout завершает все сессии пользователя
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.ForceLogout(c.Request.Context(), userID); err != nil {
+		adminErrorResponse(c, err, "Failed to logout user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User sessions revoked successfully",
+	})
+}
+
+// ForcePasswordReset требует от пользователя сменить пароль по ссылке из письма
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.ForcePasswordReset(c.Request.Context(), userID); err != nil {
+		adminErrorResponse(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset required, reset link sent",
+	})
+}
+
+// DeleteUser назначает удаление аккаунта
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	purgeAfter, err := h.adminService.DeleteUser(c.Request.Context(), userID, req.Reason)
+	if err != nil {
+		adminErrorResponse(c, err, "Failed to delete user")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "User scheduled for deletion",
+		"purge_after": purgeAfter,
+	})
+}
+
+// UnlockUser снимает блокировку входа после неудачных попыток
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.UnlockAccount(c.Request.Context(), userID); err != nil {
+		adminErrorResponse(c, err, "Failed to unlock user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
+
+// changeStatus выполняет смену статуса с причиной из тела запроса
+func (h *AdminHandler) changeStatus(c *gin.Context, change func(ctx context.Context, userID, reason string) (*models.User, error), message, failure string) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := change(c.Request.Context(), userID, req.Reason)
+	if err != nil {
+		adminErrorResponse(c, err, failure)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"user":    adminUserResponse(user),
+	})
+}
+
+// userIDParam читает id пользователя из пути; при ошибке отвечает 400
+func userIDParam(c *gin.Context) (string, bool) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user id",
+			"details": err.Error(),
+		})
+		return "", false
+	}
+	return userID, true
+}
+
+// adminErrorResponse отвечает на ошибку операции администратора
+func adminErrorResponse(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, postgres.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case errors.Is(err, service.ErrInvalidCursor), errors.Is(err, service.ErrStatusReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   failure,
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, service.ErrAccountDeleted):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   failure,
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": failure,
+		})
+	}
+}
+
+// adminUserResponse - данные пользователя для поддержки
+func adminUserResponse(user *models.User) gin.H {
+	return gin.H{
+		"id":                      user.ID,
+		"email":                   user.Email,
+		"email_verified_at":       user.EmailVerifiedAt,
+		"username":                user.Username,
+		"display_name":            user.DisplayName,
+		"role":                    user.Role,
+		"status":                  user.Status,
+		"status_reason":           user.StatusReason,
+		"status_changed_at":       user.StatusChangedAt,
+		"locked_until":            user.LockedUntil,
+		"password_reset_required": user.PasswordResetRequired,
+		"totp_enabled_at":         user.TOTPEnabledAt,
+		"deleted_at":              user.DeletedAt,
+		"purge_after":             user.PurgeAfter,
+		"created_at":              user.CreatedAt,
+		"updated_at":              user.UpdatedAt,
+	}
+}
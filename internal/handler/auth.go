@@ -2,8 +2,11 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 
+	"auth-service/internal/auth"
 	"auth-service/internal/models"
 	"auth-service/internal/service"
 
@@ -14,7 +17,10 @@ import (
 type AuthService interface {
 	Register(ctx context.Context, req *models.CreateUserRequest) (*service.AuthResponse, error)
 	Login(ctx context.Context, req *models.LoginRequest) (*service.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*service.AuthResponse, error)
+	Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error
 	GetProfile(ctx context.Context, userID string) (*models.User, error)
+	ChangePassword(ctx context.Context, claims *auth.Claims, req *models.ChangePasswordRequest) (*service.AuthResponse, error)
 }
 
 type AuthHandler struct {
@@ -50,15 +56,29 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	body := gin.H{
 		"message": "User registered successfully",
 		"user": gin.H{
 			"id":         authResponse.User.ID,
 			"email":      authResponse.User.Email,
+			"username":   authResponse.User.Username,
 			"created_at": authResponse.User.CreatedAt,
 		},
-		"token": authResponse.Token,
-	})
+	}
+
+	// Токены не выдаются, пока адрес не подтвержден, и аккаунту без пароля
+	if authResponse.Token == "" && req.Password == "" {
+		body["message"] = "User registered successfully, request a sign-in link via /auth/magic-link"
+		c.JSON(http.StatusCreated, body)
+		return
+	}
+	if authResponse.Token == "" {
+		body["message"] = "User registered successfully, please verify your email address"
+		c.JSON(http.StatusCreated, body)
+		return
+	}
+
+	c.JSON(http.StatusCreated, withTokens(body, authResponse))
 }
 
 // Login обрабатывает вход пользователя
@@ -77,20 +97,135 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Аутентификация пользователя
 	authResponse, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Authentication failed",
+		loginErrorResponse(c, err)
+		return
+	}
+
+	loginResponse(c, authResponse)
+}
+
+// loginErrorResponse отвечает на ошибку входа
+func loginErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Email address is not verified",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrAccountDeleted) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account is scheduled for deletion",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrAccountSuspended) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account is suspended",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrPasswordResetRequired) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Password reset required",
 			"details": err.Error(),
 		})
 		return
 	}
+	if errors.Is(err, service.ErrAccountLocked) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account is temporarily locked",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":   "Authentication failed",
+		"details": err.Error(),
+	})
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
+// loginResponse отвечает на успешный вход
+func loginResponse(c *gin.Context, authResponse *service.AuthResponse) {
+	message := "Login successful"
+	if authResponse.MFAToken != "" {
+		message = "Two-factor authentication required"
+	}
+
+	c.JSON(http.StatusOK, withTokens(gin.H{
+		"message": message,
 		"user": gin.H{
-			"id":    authResponse.User.ID,
-			"email": authResponse.User.Email,
+			"id":       authResponse.User.ID,
+			"email":    authResponse.User.Email,
+			"username": authResponse.User.Username,
 		},
-		"token": authResponse.Token,
+	}, authResponse))
+}
+
+// Refresh обменивает refresh токен на новую пару токенов
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	// Валидация входных данных
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Invalid refresh token",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to refresh token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withTokens(gin.H{
+		"message": "Token refreshed successfully",
+	}, authResponse))
+}
+
+// Logout отзывает текущий access токен и, если передан, refresh токен
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	// Тело запроса необязательно
+	var req models.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), claims.(*auth.Claims), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to logout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logout successful",
 	})
 }
 
@@ -116,11 +251,71 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
-		},
+		"user": profileResponse(user),
 	})
-}
\ No newline at end of file
+}
+
+// ChangePassword меняет пароль текущего пользователя. Остальные сессии завершаются,
+// в ответе - новая пара токенов для текущей.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists || claims.(*auth.Claims).Principal() != auth.PrincipalUser {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.authService.ChangePassword(c.Request.Context(), claims.(*auth.Claims), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountLocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Account is temporarily locked",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrWrongPassword) || errors.Is(err, service.ErrPasswordUnchanged) || errors.Is(err, service.ErrPasswordTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to change password",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to change password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withTokens(gin.H{
+		"message": "Password changed successfully",
+	}, authResponse))
+}
+
+// withTokens добавляет в ответ access и refresh токены со сроками действия,
+// а если нужен второй шаг входа - MFA токен для /auth/mfa/verify
+func withTokens(body gin.H, authResponse *service.AuthResponse) gin.H {
+	if authResponse.MFAToken != "" {
+		body["mfa_required"] = true
+		body["mfa_token"] = authResponse.MFAToken
+		body["mfa_token_expires_at"] = authResponse.MFATokenExpiresAt
+		return body
+	}
+
+	body["token"] = authResponse.Token
+	body["token_expires_at"] = authResponse.TokenExpiresAt
+	body["refresh_token"] = authResponse.RefreshToken
+	body["refresh_token_expires_at"] = authResponse.RefreshTokenExpiresAt
+	body["scope"] = authResponse.Scope
+	return body
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataExportService интерфейс выгрузки персональных данных
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID string) (*service.DataExportFile, bool, error)
+	DownloadExport(ctx context.Context, token string) (*service.DataExportFile, error)
+}
+
+type DataExportHandler struct {
+	dataExportService DataExportService
+}
+
+func NewDataExportHandler(dataExportService DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+	}
+}
+
+// ExportData отдает выгрузку данных текущего пользователя файлом
+// или, если она большая, собирает ее в фоне и отправляет ссылку на email
+func (h *DataExportHandler) ExportData(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.DataExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	file, queued, err := h.dataExportService.RequestExport(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to export data",
+		})
+		return
+	}
+
+	if queued {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Export is being prepared, a download link will be sent to your email",
+		})
+		return
+	}
+
+	writeDataExport(c, file, req.Format)
+}
+
+// DownloadExport отдает выгрузку по одноразовой ссылке из письма
+func (h *DataExportHandler) DownloadExport(c *gin.Context) {
+	var req models.DataExportDownloadRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	file, err := h.dataExportService.DownloadExport(c.Request.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExportToken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to download export",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to download export",
+		})
+		return
+	}
+
+	writeDataExport(c, file, req.Format)
+}
+
+// writeDataExport отдает выгрузку вложением: JSON или zip архив с JSON внутри
+func writeDataExport(c *gin.Context, file *service.DataExportFile, format string) {
+	data := file.Data
+	filename := fmt.Sprintf("account-export-%s", file.UserID)
+	contentType := "application/json"
+
+	if format == "zip" {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		entry, err := archive.Create(filename + ".json")
+		if err == nil {
+			_, err = entry.Write(data)
+		}
+		if err == nil {
+			err = archive.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to export data",
+			})
+			return
+		}
+
+		data = buf.Bytes()
+		filename += ".zip"
+		contentType = "application/zip"
+	} else {
+		filename += ".json"
+	}
+
+	// Выгрузка содержит персональные данные и не должна кешироваться
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailChangeService интерфейс смены email с подтверждением
+type EmailChangeService interface {
+	RequestEmailChange(ctx context.Context, userID string, req *models.ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	UndoEmailChange(ctx context.Context, token string) error
+}
+
+type EmailChangeHandler struct {
+	emailChangeService EmailChangeService
+}
+
+func NewEmailChangeHandler(emailChangeService EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+	}
+}
+
+// RequestEmailChange отправляет ссылку для подтверждения на новый адрес текущего пользователя
+func (h *EmailChangeHandler) RequestEmailChange(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.emailChangeService.RequestEmailChange(c.Request.Context(), userID.(string), &req); err != nil {
+		if errors.Is(err, service.ErrAccountLocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Account is temporarily locked",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrWrongPassword) || errors.Is(err, service.ErrInvalidOTP) || errors.Is(err, service.ErrEmailUnchanged) || errors.Is(err, service.ErrEmailTaken) || errors.Is(err, service.ErrInvalidEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to change email",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to change email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Confirmation link has been sent to the new address",
+	})
+}
+
+// ConfirmEmailChange меняет адрес по ссылке из письма на новый адрес
+func (h *EmailChangeHandler) ConfirmEmailChange(c *gin.Context) {
+	h.handleToken(c, h.emailChangeService.ConfirmEmailChange, "Email changed successfully")
+}
+
+// UndoEmailChange отменяет смену адреса по ссылке из письма на прежний адрес
+func (h *EmailChangeHandler) UndoEmailChange(c *gin.Context) {
+	h.handleToken(c, h.emailChangeService.UndoEmailChange, "Email change has been cancelled")
+}
+
+// handleToken принимает токен из ссылки (GET) или JSON (POST) и выполняет действие
+func (h *EmailChangeHandler) handleToken(c *gin.Context, action func(ctx context.Context, token string) error, message string) {
+	var req models.EmailChangeTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := action(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidEmailChangeToken) || errors.Is(err, service.ErrEmailTaken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to change email",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to change email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailOTPService интерфейс входа и подтверждения действий по одноразовому коду из письма
+type EmailOTPService interface {
+	RequestLoginCode(ctx context.Context, email string) error
+	LoginWithCode(ctx context.Context, email, code string) (*service.AuthResponse, error)
+	RequestReauthCode(ctx context.Context, userID string) error
+}
+
+type EmailOTPHandler struct {
+	otpService EmailOTPService
+}
+
+func NewEmailOTPHandler(otpService EmailOTPService) *EmailOTPHandler {
+	return &EmailOTPHandler{
+		otpService: otpService,
+	}
+}
+
+// RequestLoginCode отправляет шестизначный код для входа.
+// Ответ не зависит от того, зарегистрирован ли адрес.
+func (h *EmailOTPHandler) RequestLoginCode(c *gin.Context) {
+	var req models.EmailOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.otpService.RequestLoginCode(c.Request.Context(), req.Email); err != nil {
+		if errors.Is(err, service.ErrTooManyEmailRequests) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Failed to send sign-in code",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send sign-in code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address is registered, a sign-in code has been sent",
+	})
+}
+
+// LoginWithCode выполняет вход по коду из письма
+func (h *EmailOTPHandler) LoginWithCode(c *gin.Context) {
+	var req models.EmailOTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.otpService.LoginWithCode(c.Request.Context(), req.Email, req.Code)
+	if err != nil {
+		loginErrorResponse(c, err)
+		return
+	}
+
+	loginResponse(c, authResponse)
+}
+
+// RequestReauthCode отправляет текущему пользователю без пароля код для подтверждения
+// удаления аккаунта и смены email
+func (h *EmailOTPHandler) RequestReauthCode(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.otpService.RequestReauthCode(c.Request.Context(), userID.(string)); err != nil {
+		if errors.Is(err, service.ErrPasswordReauthRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to send confirmation code",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrTooManyEmailRequests) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Failed to send confirmation code",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send confirmation code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Confirmation code has been sent",
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Секрет устройства для ссылки входа: браузер хранит его в cookie,
+// остальные клиенты передают значение device_binding из ответа в заголовке
+const (
+	magicLinkBindingCookie = "magic_link_binding"
+	magicLinkBindingHeader = "X-Magic-Link-Binding"
+	magicLinkCookiePath    = "/auth/magic-link"
+)
+
+// MagicLinkService интерфейс входа по ссылке из письма
+type MagicLinkService interface {
+	RequestMagicLink(ctx context.Context, email string) (string, error)
+	VerifyMagicLink(ctx context.Context, token, binding string) (*service.AuthResponse, error)
+}
+
+type MagicLinkHandler struct {
+	magicLinkService MagicLinkService
+	secureCookie     bool
+}
+
+func NewMagicLinkHandler(magicLinkService MagicLinkService, secureCookie bool) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		secureCookie:     secureCookie,
+	}
+}
+
+// RequestMagicLink отправляет ссылку для входа и привязывает ее к устройству.
+// Ответ не зависит от того, зарегистрирован ли адрес.
+func (h *MagicLinkHandler) RequestMagicLink(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	binding, err := h.magicLinkService.RequestMagicLink(c.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyEmailRequests) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Failed to send sign-in link",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send sign-in link",
+		})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkBindingCookie, binding, 0, magicLinkCookiePath, "", h.secureCookie, true)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":        "If the address is registered, a sign-in link has been sent",
+		"device_binding": binding,
+	})
+}
+
+// VerifyMagicLink выполняет вход по токену из ссылки (GET по ссылке или POST с JSON)
+func (h *MagicLinkHandler) VerifyMagicLink(c *gin.Context) {
+	var req models.MagicLinkVerifyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	binding := c.GetHeader(magicLinkBindingHeader)
+	if binding == "" {
+		binding, _ = c.Cookie(magicLinkBindingCookie)
+	}
+
+	authResponse, err := h.magicLinkService.VerifyMagicLink(c.Request.Context(), req.Token, binding)
+	if err != nil {
+		loginErrorResponse(c, err)
+		return
+	}
+
+	// Ссылка использована - секрет устройства больше не нужен
+	c.SetCookie(magicLinkBindingCookie, "", -1, magicLinkCookiePath, "", h.secureCookie, true)
+
+	loginResponse(c, authResponse)
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAService интерфейс второго шага входа
+type MFAService interface {
+	VerifyMFA(ctx context.Context, mfaToken, code string) (*service.AuthResponse, error)
+}
+
+// TOTPService интерфейс подключения двухфакторной аутентификации
+type TOTPService interface {
+	EnrollTOTP(ctx context.Context, userID string) (*service.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) error
+	DisableTOTP(ctx context.Context, userID, code string) error
+}
+
+type MFAHandler struct {
+	mfaService  MFAService
+	totpService TOTPService
+}
+
+func NewMFAHandler(mfaService MFAService, totpService TOTPService) *MFAHandler {
+	return &MFAHandler{
+		mfaService:  mfaService,
+		totpService: totpService,
+	}
+}
+
+// VerifyMFA обменивает MFA токен из ответа /auth/login и код из приложения на пару токенов
+func (h *MFAHandler) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authResponse, err := h.mfaService.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		loginErrorResponse(c, err)
+		return
+	}
+
+	loginResponse(c, authResponse)
+}
+
+// EnrollTOTP начинает подключение TOTP: возвращает секрет, otpauth:// URI и QR-код.
+// TOTP включается только после подтверждения кода через ConfirmTOTP.
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	enrollment, err := h.totpService.EnrollTOTP(c.Request.Context(), userID.(string))
+	if err != nil {
+		if errors.Is(err, service.ErrTOTPAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Failed to start two-factor authentication setup",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start two-factor authentication setup",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Scan the QR code with an authenticator app and confirm with a code",
+		"secret":      enrollment.Secret,
+		"otpauth_uri": enrollment.URI,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode),
+	})
+}
+
+// ConfirmTOTP включает TOTP по первому коду из приложения
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.totpService.ConfirmTOTP(c.Request.Context(), userID.(string), req.Code); err != nil {
+		totpErrorResponse(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+	})
+}
+
+// DisableTOTP отключает TOTP (требуется текущий код из приложения)
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.totpService.DisableTOTP(c.Request.Context(), userID.(string), req.Code); err != nil {
+		totpErrorResponse(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// totpErrorResponse отвечает на ошибку подтверждения или отключения TOTP
+func totpErrorResponse(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrInvalidTOTPCode) || errors.Is(err, service.ErrTOTPNotEnrolled) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrTOTPAlreadyEnabled) || errors.Is(err, service.ErrTOTPNotEnabled) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrAccountLocked) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthService интерфейс OAuth 2.0 authorization server
+type OAuthService interface {
+	ValidateAuthorizeRequest(ctx context.Context, req *models.AuthorizeRequest) (string, error)
+	Authorize(ctx context.Context, req *models.AuthorizeRequest, login, password, totpCode string) (redirectURI, code string, err error)
+	Exchange(ctx context.Context, req *models.TokenRequest) (*service.OAuthTokenResponse, error)
+}
+
+// Introspector интерфейс проверки токенов по запросу сервисов (RFC 7662)
+type Introspector interface {
+	Introspect(ctx context.Context, clientID, clientSecret, token string) (*service.IntrospectionResponse, error)
+}
+
+// loginFormTemplate - форма входа, которую видит пользователь на /oauth/authorize.
+// Параметры запроса авторизации передаются обратно скрытыми полями.
+var loginFormTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Вход</title>
+</head>
+<body>
+<h1>Вход</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<label>Email или имя пользователя <input type="text" name="login" autocomplete="username" required autofocus></label>
+<label>Пароль <input type="password" name="password" required></label>
+<label>Код из приложения-аутентификатора, если включена двухфакторная аутентификация <input type="text" name="otp" inputmode="numeric" autocomplete="one-time-code" pattern="[0-9]{6}" maxlength="6"></label>
+<button type="submit">Войти</button>
+</form>
+</body>
+</html>
+`))
+
+type OAuthHandler struct {
+	oauthService OAuthService
+	introspector Introspector
+}
+
+func NewOAuthHandler(oauthService OAuthService, introspector Introspector) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		introspector: introspector,
+	}
+}
+
+// AuthorizeForm проверяет запрос авторизации и показывает форму входа
+func (h *OAuthHandler) AuthorizeForm(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	redirectURI, err := h.oauthService.ValidateAuthorizeRequest(c.Request.Context(), &req)
+	if err != nil {
+		h.authorizeError(c, &req, redirectURI, err)
+		return
+	}
+
+	renderLoginForm(c, http.StatusOK, &req, "")
+}
+
+// Authorize проверяет учетные данные и перенаправляет пользователя с кодом авторизации
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	redirectURI, code, err := h.oauthService.Authorize(c.Request.Context(), &req, c.PostForm("login"), c.PostForm("password"), c.PostForm("otp"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			renderLoginForm(c, http.StatusUnauthorized, &req, "Неверный логин или пароль")
+			return
+		}
+		if errors.Is(err, service.ErrMFARequired) {
+			renderLoginForm(c, http.StatusUnauthorized, &req, "Введите код из приложения-аутентификатора")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTOTPCode) {
+			renderLoginForm(c, http.StatusUnauthorized, &req, "Неверный код из приложения-аутентификатора")
+			return
+		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			renderLoginForm(c, http.StatusForbidden, &req, "Подтвердите email по ссылке из письма")
+			return
+		}
+		if errors.Is(err, service.ErrAccountDeleted) {
+			renderLoginForm(c, http.StatusForbidden, &req, "Аккаунт удален")
+			return
+		}
+		if errors.Is(err, service.ErrAccountSuspended) {
+			renderLoginForm(c, http.StatusForbidden, &req, "Аккаунт приостановлен")
+			return
+		}
+		if errors.Is(err, service.ErrPasswordResetRequired) {
+			renderLoginForm(c, http.StatusForbidden, &req, "Смените пароль по ссылке из письма")
+			return
+		}
+		if errors.Is(err, service.ErrAccountLocked) {
+			renderLoginForm(c, http.StatusForbidden, &req, "Слишком много неудачных попыток, вход временно заблокирован")
+			return
+		}
+		h.authorizeError(c, &req, redirectURI, err)
+		return
+	}
+
+	redirectWithParams(c, redirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})
+}
+
+// Token выдает токены по коду авторизации, refresh токену или client credentials
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Ответы token endpoint не должны кешироваться (RFC 6749, раздел 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req models.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             service.OAuthErrInvalidRequest,
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	// Конфиденциальные клиенты могут передать секрет через HTTP Basic (client_secret_basic)
+	if clientID, clientSecret, ok := basicClientCredentials(c); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	tokenResponse, err := h.oauthService.Exchange(c.Request.Context(), &req)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse)
+}
+
+// Introspect сообщает сервису, активен ли токен (RFC 7662)
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	clientID, clientSecret, ok := basicClientCredentials(c)
+	if !ok {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	introspection, err := h.introspector.Introspect(c.Request.Context(), clientID, clientSecret, c.PostForm("token"))
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, introspection)
+}
+
+// oauthErrorResponse отдает ошибку в формате RFC 6749, раздел 5.2
+func oauthErrorResponse(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": service.OAuthErrServerError,
+		})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == service.OAuthErrInvalidClient {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+// basicClientCredentials извлекает client_id и секрет из заголовка Authorization: Basic.
+// Значения в заголовке закодированы как application/x-www-form-urlencoded (RFC 6749, раздел 2.3.1).
+func basicClientCredentials(c *gin.Context) (clientID, clientSecret string, ok bool) {
+	rawID, rawSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		return "", "", false
+	}
+
+	clientID, err := url.QueryUnescape(rawID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err = url.QueryUnescape(rawSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, clientSecret, true
+}
+
+// authorizeError возвращает ошибку авторизации: клиенту через redirect_uri,
+// если он проверен, иначе - напрямую пользователю
+func (h *OAuthHandler) authorizeError(c *gin.Context, req *models.AuthorizeRequest, redirectURI string, err error) {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) && redirectURI != "" {
+		redirectWithParams(c, redirectURI, map[string]string{
+			"error":             oauthErr.Code,
+			"error_description": oauthErr.Description,
+			"state":             req.State,
+		})
+		return
+	}
+
+	if errors.Is(err, service.ErrUnknownClient) || errors.Is(err, service.ErrInvalidRedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid authorization request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to process authorization request",
+	})
+}
+
+// renderLoginForm отдает HTML форму входа
+func renderLoginForm(c *gin.Context, status int, req *models.AuthorizeRequest, errorMessage string) {
+	// Форму нельзя встраивать в чужие страницы (clickjacking)
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+
+	data := struct {
+		Request *models.AuthorizeRequest
+		Error   string
+	}{Request: req, Error: errorMessage}
+
+	if err := loginFormTemplate.Execute(c.Writer, data); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// redirectWithParams добавляет параметры к redirect_uri и перенаправляет пользователя
+func redirectWithParams(c *gin.Context, redirectURI string, params map[string]string) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Invalid redirect_uri",
+		})
+		return
+	}
+
+	query := target.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, target.String())
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordService интерфейс восстановления пароля
+type PasswordService interface {
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type PasswordHandler struct {
+	passwordService PasswordService
+}
+
+func NewPasswordHandler(passwordService PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+	}
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля.
+// Ответ не зависит от того, зарегистрирован ли адрес.
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.passwordService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process password reset request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address is registered, a password reset email has been sent",
+	})
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) || errors.Is(err, service.ErrPasswordTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to reset password",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password has been reset, please log in again",
+	})
+}
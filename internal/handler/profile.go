@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxProfilePatchBytes ограничивает размер тела PATCH /api/profile
+const maxProfilePatchBytes = 64 << 10
+
+// ProfileService интерфейс редактирования профиля
+type ProfileService interface {
+	UpdateProfile(ctx context.Context, userID string, patch []byte) (*models.User, error)
+}
+
+type ProfileHandler struct {
+	profileService ProfileService
+}
+
+func NewProfileHandler(profileService ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+	}
+}
+
+// UpdateProfile частично обновляет профиль текущего пользователя (JSON merge patch)
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be application/merge-patch+json",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxProfilePatchBytes)
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(c.Request.Context(), userID.(string), patch)
+	if err != nil {
+		var fieldErr *service.ProfileFieldError
+		if errors.As(err, &fieldErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid profile",
+				"field":   fieldErr.Field,
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidProfilePatch) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": profileResponse(user),
+	})
+}
+
+// profileResponse - профиль пользователя в ответах /api/profile
+func profileResponse(user *models.User) gin.H {
+	return gin.H{
+		"id":             user.ID,
+		"email":          user.Email,
+		"email_verified": user.IsEmailVerified(),
+		"username":       user.Username,
+		"display_name":   user.DisplayName,
+		"avatar_url":     user.AvatarURL,
+		"locale":         user.Locale,
+		"timezone":       user.Timezone,
+		"metadata":       user.Metadata,
+		"created_at":     user.CreatedAt,
+		"updated_at":     user.UpdatedAt,
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"auth-service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ключи кешируются недолго, чтобы клиенты быстро подхватывали ротацию;
+// метаданные issuer меняются редко
+const (
+	jwksCacheControl      = "public, max-age=300"
+	discoveryCacheControl = "public, max-age=3600"
+)
+
+// KeyPublisher интерфейс для публикации ключей проверки токенов
+type KeyPublisher interface {
+	JWKS() auth.JWKS
+	Issuer() string
+	SigningAlgorithms() []string
+}
+
+type WellKnownHandler struct {
+	keys KeyPublisher
+}
+
+func NewWellKnownHandler(keys KeyPublisher) *WellKnownHandler {
+	return &WellKnownHandler{
+		keys: keys,
+	}
+}
+
+// JWKS отдает публичные ключи подписи токенов
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// OpenIDConfiguration отдает метаданные OpenID Connect discovery
+func (h *WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	issuer := strings.TrimSuffix(h.keys.Issuer(), "/")
+
+	c.Header("Cache-Control", discoveryCacheControl)
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"introspection_endpoint":                issuer + "/oauth/introspect",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_basic", "client_secret_post"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": h.keys.SigningAlgorithms(),
+		"claims_supported":                      []string{"iss", "sub", "exp", "iat", "nbf", "aud", "user_id", "email", "client_id", "scope"},
+	})
+}
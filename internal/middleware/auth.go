@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -9,8 +10,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// RevocationChecker интерфейс для проверки отозванных токенов
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error)
+}
+
 // AuthMiddleware проверяет JWT токен в заголовке Authorization
-func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
+func AuthMiddleware(jwtService *auth.JWTService, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Получаем заголовок Authorization
 		authHeader := c.GetHeader("Authorization")
@@ -45,9 +51,105 @@ func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
 			return
 		}
 
-		// Сохраняем данные пользователя в контекст
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
+		// Проверяем, не был ли токен отозван (logout, смена пароля, приостановка или удаление аккаунта)
+		revoked, err := revocations.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check token revocation",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
+		// Сохраняем данные субъекта в контекст: пользователя или сервиса
+		c.Set("claims", claims)
+		c.Set("principal_type", claims.Principal())
+		if claims.Principal() == auth.PrincipalService {
+			c.Set("client_id", claims.ClientID)
+		} else {
+			c.Set("user_id", claims.UserID)
+			c.Set("user_email", claims.Email)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScopes пропускает запрос, только если токену выданы все указанные scopes.
+// Используется после AuthMiddleware.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	required := strings.Join(scopes, " ")
+
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		claims, ok := value.(*auth.Claims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				// Формат ошибки описан в RFC 6750, раздел 3.1
+				c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+required+`"`)
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "insufficient_scope",
+					"details": "token requires scope: " + required,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// AdminChecker интерфейс проверки доступа к операциям администратора
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, claims *auth.Claims) (bool, error)
+}
+
+// RequireAdmin пропускает запрос, только если субъект токена - администратор.
+// Используется после AuthMiddleware.
+func RequireAdmin(admins AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		claims, ok := value.(*auth.Claims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		// Роль читается при каждом запросе, чтобы снятие роли действовало сразу
+		isAdmin, err := admins.IsAdmin(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check permissions",
+			})
+			c.Abort()
+			return
+		}
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Access denied",
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListUsersRequest - параметры списка пользователей в /admin/users
+type ListUsersRequest struct {
+	Status      string    `form:"status" binding:"omitempty,oneof=pending active suspended deleted"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Email - префикс адреса для поиска
+	Email  string `form:"email" binding:"max=255"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// UserListFilter - условия выборки пользователей. Пустые поля не ограничивают выборку.
+// Пользователи упорядочены от новых к старым; After - последний пользователь предыдущей страницы.
+type UserListFilter struct {
+	Status      UserStatus
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	EmailPrefix string
+	After       *UserCursor
+	Limit       int
+}
+
+// UserCursor - позиция в списке пользователей
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// AdminReasonRequest - причина действия администратора, сохраняется в истории статусов
+type AdminReasonRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExport - все данные, которые сервис хранит о пользователе.
+// Хеши паролей и токенов в выгрузку не попадают.
+type DataExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    *User          `json:"profile"`
+	Sessions   []RefreshToken `json:"sessions"`
+	// LoginHistory - успешные входы, восстановленные по сессиям
+	LoginHistory  []DataExportLogin       `json:"login_history"`
+	LoginSecurity DataExportLoginSecurity `json:"login_security"`
+	// AuditLog - журнал переходов статуса аккаунта
+	AuditLog   []UserStatusChange `json:"audit_log"`
+	EmailLinks []UserToken        `json:"email_links"`
+	// NotRecorded - категории данных, которые сервис не хранит и поэтому не выгружает
+	NotRecorded map[string]string `json:"not_recorded"`
+}
+
+// DataExportLogin - успешный вход: каждый вход начинает новое семейство refresh токенов.
+// ClientID - OAuth клиент, которому пользователь выдал доступ; пуст для входа в сам сервис.
+type DataExportLogin struct {
+	SessionID  uuid.UUID `json:"session_id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+}
+
+// DataExportLoginSecurity - текущие счетчики неудачных входов и блокировка
+type DataExportLoginSecurity struct {
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockoutCount        int        `json:"lockout_count"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+}
+
+// DataExportRequest - параметры выгрузки; format - json или zip
+type DataExportRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+// DataExportDownloadRequest - ссылка на выгрузку из письма
+type DataExportDownloadRequest struct {
+	Token  string `form:"token" binding:"required"`
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
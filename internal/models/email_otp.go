@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Назначения одноразовых кодов из писем. Код одного назначения не принимается для другого.
+const (
+	EmailOTPLogin = "login"
+	// EmailOTPReauth подтверждает чувствительные действия в аккаунте без пароля
+	EmailOTPReauth = "reauth"
+)
+
+// EmailOTP - одноразовый код, отправленный пользователю по email.
+// Хранится только HMAC кода; Email - адрес, на который ушло письмо,
+// Attempts - сколько раз код уже проверяли.
+type EmailOTP struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Purpose   string    `json:"purpose" db:"purpose"`
+	CodeHash  string    `json:"-" db:"code_hash"`
+	Email     string    `json:"email" db:"email"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthClient - зарегистрированное OAuth приложение.
+// Публичные клиенты (SPA, мобильные приложения) не имеют секрета,
+// конфиденциальные (backend сервисы) аутентифицируются client_id и секретом.
+type OAuthClient struct {
+	ClientID     string    `json:"client_id" db:"client_id"`
+	Name         string    `json:"name" db:"name"`
+	RedirectURIs []string  `json:"redirect_uris" db:"redirect_uris"`
+	SecretHash   string    `json:"-" db:"secret_hash"`
+	Scopes       []string  `json:"scopes" db:"scopes"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// IsConfidential сообщает, должен ли клиент аутентифицироваться секретом
+func (c *OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// AuthorizationCode - одноразовый код авторизации; хранится только хеш кода
+type AuthorizationCode struct {
+	CodeHash            string     `json:"-" db:"code_hash"`
+	ClientID            string     `json:"client_id" db:"client_id"`
+	UserID              uuid.UUID  `json:"user_id" db:"user_id"`
+	RedirectURI         string     `json:"redirect_uri" db:"redirect_uri"`
+	RedirectURIProvided bool       `json:"-" db:"redirect_uri_provided"`
+	Scope               string     `json:"scope" db:"scope"`
+	CodeChallenge       string     `json:"-" db:"code_challenge"`
+	CodeChallengeMethod string     `json:"-" db:"code_challenge_method"`
+	ExpiresAt           time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt              *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuthorizeRequest - параметры запроса /oauth/authorize
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// TokenRequest - параметры запроса /oauth/token
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken хранит хеш непрозрачного refresh токена.
+// Все токены, полученные ротацией из одного логина, образуют семейство (FamilyID).
+// ClientID - OAuth клиент, получивший семейство; пустой у токенов первой стороны.
+// Scope - запрошенные scopes; access токен получает их с учетом текущего состояния пользователя.
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"`
+	ClientID   string     `json:"client_id,omitempty" db:"client_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scope      string     `json:"scope" db:"scope"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -7,19 +7,182 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+	TokenGeneration int        `json:"-" db:"token_generation"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	PurgeAfter      *time.Time `json:"purge_after,omitempty" db:"purge_after"`
+	// Статус аккаунта, причина и время последнего перехода
+	Status          UserStatus `json:"status" db:"status"`
+	StatusReason    *string    `json:"status_reason,omitempty" db:"status_reason"`
+	StatusChangedAt time.Time  `json:"status_changed_at" db:"status_changed_at"`
+	Role            UserRole   `json:"role" db:"role"`
+	// PasswordResetRequired - администратор потребовал сменить пароль
+	PasswordResetRequired bool `json:"password_reset_required" db:"password_reset_required"`
+	// Неудачные попытки входа подряд, число блокировок подряд и срок текущей блокировки
+	FailedLoginAttempts int        `json:"-" db:"failed_login_attempts"`
+	LockoutCount        int        `json:"-" db:"lockout_count"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	// TOTP: зашифрованный секрет, время включения (после подтверждения первого кода)
+	// и период последнего принятого кода
+	TOTPSecret       string     `json:"-" db:"totp_secret"`
+	TOTPEnabledAt    *time.Time `json:"totp_enabled_at,omitempty" db:"totp_enabled_at"`
+	TOTPLastUsedStep int64      `json:"-" db:"totp_last_used_step"`
+	Profile
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Profile - данные профиля, которые пользователь меняет сам через PATCH /api/profile
+type Profile struct {
+	// Username - необязательный уникальный логин; сравнивается без учета регистра
+	Username    *string        `json:"username" db:"username"`
+	DisplayName *string        `json:"display_name" db:"display_name"`
+	AvatarURL   *string        `json:"avatar_url" db:"avatar_url"`
+	Locale      *string        `json:"locale" db:"locale"`
+	Timezone    *string        `json:"timezone" db:"timezone"`
+	Metadata    map[string]any `json:"metadata" db:"metadata"`
+}
+
+// UserRole - роль пользователя
+type UserRole string
+
+// Роли пользователей
+const (
+	RoleUser  UserRole = "user"
+	RoleAdmin UserRole = "admin"
+)
+
+// IsAdmin сообщает, является ли пользователь администратором
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// HasPassword сообщает, задан ли пароль; без пароля вход возможен только по ссылке из письма
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// IsDeleted сообщает, запрошено ли удаление аккаунта
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+// IsSuspended сообщает, приостановлен ли аккаунт
+func (u *User) IsSuspended() bool {
+	return u.Status == UserStatusSuspended
+}
+
+// IsLocked сообщает, заблокирован ли вход после неудачных попыток
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// TOTPEnabled сообщает, включена ли двухфакторная аутентификация по TOTP
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// IsEmailVerified сообщает, подтвердил ли пользователь свой адрес
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// CreateUserRequest - регистрация; без пароля создается аккаунт для входа по ссылке из письма
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username string `json:"username"`
+	Password string `json:"password" binding:"omitempty,min=6"`
 }
 
+// LoginRequest - вход по email или username. Identifier принимает и то и другое,
+// поле email оставлено для существующих клиентов.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Identifier string `json:"identifier" binding:"required_without=Email"`
+	Email      string `json:"email" binding:"required_without=Identifier"`
+	Password   string `json:"password" binding:"required,min=6"`
+}
+
+// Login возвращает email или username, по которому выполняется вход
+func (r *LoginRequest) Login() string {
+	if r.Identifier != "" {
+		return r.Identifier
+	}
+	return r.Email
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+type EmailOTPRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type EmailOTPVerifyRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// MFAVerifyRequest - второй шаг входа: токен из ответа /auth/login и код из приложения
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
 }
+
+// ChangePasswordRequest - смена пароля. Аккаунт без пароля задает первый пароль
+// без current_password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest - смена адреса. Действие подтверждается паролем, а в аккаунте
+// без пароля - кодом из письма (POST /api/reauth/code).
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// DeleteAccountRequest - удаление аккаунта, подтверждается как ChangeEmailRequest
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RestoreAccountRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
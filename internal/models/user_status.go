@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserStatus - состояние аккаунта в жизненном цикле пользователя
+type UserStatus string
+
+// Статусы аккаунта
+const (
+	// UserStatusPending - адрес еще не подтвержден; вход определяется политикой подтверждения
+	UserStatusPending UserStatus = "pending"
+	UserStatusActive  UserStatus = "active"
+	// UserStatusSuspended - аккаунт приостановлен: вход запрещен, выданные токены не принимаются
+	UserStatusSuspended UserStatus = "suspended"
+	// UserStatusDeleted - запрошено удаление, до purge_after его можно отменить
+	UserStatusDeleted UserStatus = "deleted"
+)
+
+// Причины переходов, которые выполняет сам сервис
+const (
+	StatusReasonEmailVerified     = "email_verified"
+	StatusReasonDeletionRequested = "deletion_requested"
+	StatusReasonDeletionCancelled = "deletion_cancelled"
+	StatusReasonImported          = "imported"
+)
+
+// userStatusTransitions - допустимые переходы между статусами
+var userStatusTransitions = map[UserStatus][]UserStatus{
+	UserStatusPending:   {UserStatusActive, UserStatusSuspended, UserStatusDeleted},
+	UserStatusActive:    {UserStatusSuspended, UserStatusDeleted},
+	UserStatusSuspended: {UserStatusPending, UserStatusActive, UserStatusDeleted},
+	// Отмена удаления возвращает аккаунт в статус, который был до удаления
+	UserStatusDeleted: {UserStatusPending, UserStatusActive, UserStatusSuspended},
+}
+
+// IsValid сообщает, известен ли статус
+func (s UserStatus) IsValid() bool {
+	_, ok := userStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo сообщает, допустим ли переход из статуса s в next
+func (s UserStatus) CanTransitionTo(next UserStatus) bool {
+	for _, allowed := range userStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesTransitioningTo возвращает статусы, из которых допустим переход в next
+func StatusesTransitioningTo(next UserStatus) []UserStatus {
+	var from []UserStatus
+	for status := range userStatusTransitions {
+		if status.CanTransitionTo(next) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
+// UserStatusChange - запись истории переходов статуса
+type UserStatusChange struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	FromStatus UserStatus `json:"from_status" db:"from_status"`
+	ToStatus   UserStatus `json:"to_status" db:"to_status"`
+	Reason     string     `json:"reason" db:"reason"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
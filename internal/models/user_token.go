@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Назначения одноразовых токенов из писем
+const (
+	UserTokenVerifyEmail     = "verify_email"
+	UserTokenResetPassword   = "reset_password"
+	UserTokenChangeEmail     = "change_email"
+	UserTokenUndoEmailChange = "undo_email_change"
+	UserTokenDataExport      = "data_export"
+	UserTokenMagicLink       = "magic_link"
+	// UserTokenMFAChallenge - второй шаг входа с TOTP; выдается в ответе, а не письмом
+	UserTokenMFAChallenge = "mfa_challenge"
+)
+
+// UserToken - одноразовый токен, отправленный пользователю по email.
+// Хранится только хеш; Email - адрес, на который ушло письмо.
+// BindingHash - хеш секрета устройства, запросившего письмо; пуст у непривязанных токенов.
+type UserToken struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Purpose     string     `json:"purpose" db:"purpose"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	BindingHash string     `json:"-" db:"binding_hash"`
+	Email       string     `json:"email" db:"email"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+)
+
+type DataExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepository(db *pgxpool.Pool) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// SaveDataExport сохраняет собранную выгрузку, привязанную к токену ссылки из письма
+func (r *DataExportRepository) SaveDataExport(ctx context.Context, tokenID, userID uuid.UUID, data []byte, expiresAt time.Time) error {
+	query := `
+		INSERT INTO data_export_files (token_id, user_id, data, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Exec(ctx, query, tokenID, userID, data, expiresAt); err != nil {
+		return fmt.Errorf("failed to save data export: %w", err)
+	}
+
+	return nil
+}
+
+// TakeDataExport возвращает выгрузку, привязанную к токену, и удаляет ее:
+// копия персональных данных не хранится дольше, чем нужно для скачивания
+func (r *DataExportRepository) TakeDataExport(ctx context.Context, tokenID uuid.UUID) ([]byte, error) {
+	var data []byte
+
+	query := `
+		DELETE FROM data_export_files
+		WHERE token_id = $1 AND expires_at > NOW()
+		RETURNING data
+	`
+
+	err := r.db.QueryRow(ctx, query, tokenID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDataExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take data export: %w", err)
+	}
+
+	return data, nil
+}
+
+// DeleteExpiredDataExports удаляет выгрузки, которые так и не скачали до истечения ссылки
+func (r *DataExportRepository) DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM data_export_files WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
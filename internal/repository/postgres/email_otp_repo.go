@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrEmailOTPNotFound = errors.New("email otp not found")
+)
+
+type EmailOTPRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailOTPRepository(db *pgxpool.Pool) *EmailOTPRepository {
+	return &EmailOTPRepository{db: db}
+}
+
+// CreateEmailOTP сохраняет новый код. Прежний код того же назначения заменяется
+// вместе со счетчиком попыток: действует только код из последнего письма.
+func (r *EmailOTPRepository) CreateEmailOTP(ctx context.Context, otp *models.EmailOTP) error {
+	query := `
+		INSERT INTO email_otps (id, user_id, purpose, code_hash, email, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, $7)
+		ON CONFLICT (user_id, purpose) DO UPDATE SET
+			id = EXCLUDED.id,
+			code_hash = EXCLUDED.code_hash,
+			email = EXCLUDED.email,
+			attempts = 0,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.db.Exec(ctx, query, otp.ID, otp.UserID, otp.Purpose, otp.CodeHash, otp.Email, otp.ExpiresAt, otp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create email otp: %w", err)
+	}
+
+	return nil
+}
+
+// AttemptEmailOTP атомарно учитывает попытку проверки кода и возвращает его.
+// Код, проверенный maxAttempts раз, больше не находится (ErrEmailOTPNotFound),
+// поэтому параллельные запросы не могут перебрать больше попыток.
+func (r *EmailOTPRepository) AttemptEmailOTP(ctx context.Context, userID uuid.UUID, purpose string, maxAttempts int) (*models.EmailOTP, error) {
+	var otp models.EmailOTP
+
+	query := `
+		UPDATE email_otps
+		SET attempts = attempts + 1
+		WHERE user_id = $1 AND purpose = $2 AND attempts < $3
+		RETURNING id, user_id, purpose, code_hash, email, attempts, expires_at, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, userID, purpose, maxAttempts).Scan(
+		&otp.ID,
+		&otp.UserID,
+		&otp.Purpose,
+		&otp.CodeHash,
+		&otp.Email,
+		&otp.Attempts,
+		&otp.ExpiresAt,
+		&otp.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEmailOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to attempt email otp: %w", err)
+	}
+
+	return &otp, nil
+}
+
+// DeleteEmailOTP удаляет использованный код. Если код уже удален параллельным
+// запросом или заменен новым, возвращает ErrEmailOTPNotFound.
+func (r *EmailOTPRepository) DeleteEmailOTP(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM email_otps WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete email otp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEmailOTPNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EmailRequestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailRequestRepository(db *pgxpool.Pool) *EmailRequestRepository {
+	return &EmailRequestRepository{db: db}
+}
+
+// AllowEmailRequest учитывает запрос письма указанного назначения на адрес, если с since
+// их было меньше limit, и сообщает, учтен ли запрос. Отклоненные запросы не сохраняются,
+// записи старше since удаляются.
+func (r *EmailRequestRepository) AllowEmailRequest(ctx context.Context, email, purpose string, limit int, since time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Параллельные запросы на один адрес считаются по очереди, чтобы не превысить лимит
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, email, purpose); err != nil {
+		return false, fmt.Errorf("failed to lock email requests: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM email_requests
+		WHERE email = $1 AND purpose = $2 AND created_at <= $3
+	`, email, purpose, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete old email requests: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO email_requests (email, purpose)
+		SELECT $1, $2
+		WHERE (SELECT COUNT(*) FROM email_requests WHERE email = $1 AND purpose = $2 AND created_at > $3) < $4
+	`, email, purpose, since, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to record email request: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit email request: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
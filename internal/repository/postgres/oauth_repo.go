@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrClientNotFound            = errors.New("oauth client not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found or already used")
+)
+
+type OAuthRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOAuthRepository(db *pgxpool.Pool) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+// GetClient получает зарегистрированного OAuth клиента по client_id
+func (r *OAuthRepository) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+
+	query := `
+		SELECT client_id, name, redirect_uris, COALESCE(secret_hash, ''), scopes, created_at, updated_at
+		FROM oauth_clients
+		WHERE client_id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, clientID).Scan(
+		&client.ClientID,
+		&client.Name,
+		&client.RedirectURIs,
+		&client.SecretHash,
+		&client.Scopes,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+
+	return &client, nil
+}
+
+// CreateAuthorizationCode сохраняет выданный код авторизации
+func (r *OAuthRepository) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scope, code_challenge, code_challenge_method, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.RedirectURIProvided,
+		code.Scope,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		code.ExpiresAt,
+		code.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode атомарно помечает код использованным и возвращает его.
+// Повторный вызов для того же кода возвращает ErrAuthorizationCodeNotFound.
+func (r *OAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+
+	query := `
+		UPDATE oauth_authorization_codes
+		SET used_at = NOW()
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scope, code_challenge,
+			code_challenge_method, expires_at, used_at, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, codeHash).Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.RedirectURIProvided,
+		&code.Scope,
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.ExpiresAt,
+		&code.UsedAt,
+		&code.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAuthorizationCodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume authorization code: %w", err)
+	}
+
+	return &code, nil
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+)
+
+type RefreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(db *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// CreateRefreshToken сохраняет новый refresh токен
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, client_id, token_hash, scope, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.ClientID,
+		token.TokenHash,
+		token.Scope,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// GetRefreshTokenByHash получает refresh токен по его хешу
+func (r *RefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	query := `
+		SELECT id, user_id, family_id, COALESCE(client_id, ''), token_hash, scope, expires_at, used_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ClientID,
+		&token.TokenHash,
+		&token.Scope,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.ReplacedBy,
+		&token.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken атомарно помечает старый токен использованным и сохраняет новый.
+// Если старый токен уже был использован или отозван, возвращает ErrRefreshTokenUsed.
+func (r *RefreshTokenRepository) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = NOW(), replaced_by = $2
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`, oldID, next.ID)
+	if err != nil {
+		return fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRefreshTokenUsed
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, client_id, token_hash, scope, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)
+	`, next.ID, next.UserID, next.FamilyID, next.ClientID, next.TokenHash, next.Scope, next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily отзывает все токены семейства
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, query, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens отзывает все refresh токены пользователя (выход со всех устройств)
+func (r *RefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// ListUserRefreshTokens возвращает все refresh токены пользователя, новые первыми
+func (r *RefreshTokenRepository) ListUserRefreshTokens(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, COALESCE(client_id, ''), token_hash, scope, expires_at, used_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.RefreshToken{}
+	for rows.Next() {
+		var token models.RefreshToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.FamilyID,
+			&token.ClientID,
+			&token.TokenHash,
+			&token.Scope,
+			&token.ExpiresAt,
+			&token.UsedAt,
+			&token.RevokedAt,
+			&token.ReplacedBy,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	return tokens, nil
+}
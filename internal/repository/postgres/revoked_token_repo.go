@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RevokedTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRevokedTokenRepository(db *pgxpool.Pool) *RevokedTokenRepository {
+	return &RevokedTokenRepository{db: db}
+}
+
+// RevokeToken добавляет jti в denylist до момента истечения токена
+func (r *RevokedTokenRepository) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, NOW())
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked проверяет наличие jti в denylist
+func (r *RevokedTokenRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+	if err := r.db.QueryRow(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpiredRevokedTokens удаляет записи о токенах, которые истекли бы и без отзыва
+func (r *RevokedTokenRepository) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
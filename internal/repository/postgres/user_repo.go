@@ -4,19 +4,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"auth-service/internal/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/idna"
 )
 
 // Доменные ошибки
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrEmailTaken    = errors.New("email is already in use")
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrStatusTransition - пользователь не найден или переход из его текущего статуса недопустим
+	ErrStatusTransition = errors.New("user status transition not allowed")
+	// ErrTOTPStateChanged - пользователь не найден или настройки TOTP изменились параллельно
+	ErrTOTPStateChanged = errors.New("totp settings changed concurrently")
 )
 
+// uniqueViolation - код ошибки Postgres при нарушении уникальности
+const uniqueViolation = "23505"
+
+// usernameIndex - уникальный индекс LOWER(username)
+const usernameIndex = "idx_users_username_lower"
+
+// isUsernameTaken сообщает, нарушена ли уникальность username
+func isUsernameTaken(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == usernameIndex
+}
+
 type UserRepository struct {
 	db *pgxpool.Pool
 }
@@ -31,23 +52,35 @@ func (r *UserRepository) CreateUser(ctx context.Context, req *models.CreateUserR
 		ID:           uuid.New(),
 		Email:        req.Email,
 		PasswordHash: passwordHash,
+		Status:       models.UserStatusPending,
+		Role:         models.RoleUser,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
+	user.StatusChangedAt = user.CreatedAt
+	if req.Username != "" {
+		user.Username = &req.Username
+	}
 
 	query := `
-		INSERT INTO users (id, email, password_hash, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (id, email, username, password_hash, status, status_changed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, email, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
 		user.ID,
 		user.Email,
-		user.PasswordHash,
+		user.Username,
+		nullIfEmpty(user.PasswordHash),
+		user.Status,
+		user.StatusChangedAt,
 		user.CreatedAt,
 		user.UpdatedAt,
 	).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	if isUsernameTaken(err) {
+		return nil, ErrUsernameTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -57,38 +90,57 @@ func (r *UserRepository) CreateUser(ctx context.Context, req *models.CreateUserR
 
 // GetUserByEmail получает пользователя по email
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
+	// Адрес в канонической форме предпочтительнее прежней, если есть оба
+	query := `SELECT ` + userColumns + ` FROM users WHERE email IN ($1, $2) ORDER BY email = $1 DESC LIMIT 1`
 
-	query := `
-		SELECT id, email, password_hash, created_at, updated_at
-		FROM users 
-		WHERE email = $1
-	`
+	user, err := scanUser(r.db.QueryRow(ctx, query, email, legacyIDNEmail(email)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
 
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	return user, nil
+}
 
+// legacyIDNEmail возвращает адрес с доменом IDN в Unicode. В такой форме адреса
+// оставляет миграция 012: в punycode их переписывает cmd/email-report -apply, а до
+// этого аккаунт находится по прежней форме. Адрес без IDN домена возвращается как есть.
+func legacyIDNEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || !strings.Contains(email[at+1:], "xn--") {
+		return email
+	}
+
+	domain, err := idna.ToUnicode(email[at+1:])
+	if err != nil {
+		return email
+	}
+	return email[:at+1] + strings.ToLower(domain)
+}
+
+// GetUserByUsername получает пользователя по username без учета регистра
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(username) = LOWER($1)`
+
+	user, err := scanUser(r.db.QueryRow(ctx, query, username))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by email: %w", err)
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // UserExists проверяет существует ли пользователь с таким email
 func (r *UserRepository) UserExists(ctx context.Context, email string) (bool, error) {
 	var exists bool
 
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
-	err := r.db.QueryRow(ctx, query, email).Scan(&exists)
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email IN ($1, $2))`
+	err := r.db.QueryRow(ctx, query, email, legacyIDNEmail(email)).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check user existence: %w", err)
 	}
@@ -98,28 +150,803 @@ func (r *UserRepository) UserExists(ctx context.Context, email string) (bool, er
 
 // GetUserByID получает пользователя по ID
 func (r *UserRepository) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
-	var user models.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanUser(r.db.QueryRow(ctx, query, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	}
+
+	return user, nil
+}
+
+// MarkEmailVerified отмечает адрес подтвержденным, если он не изменился с момента отправки письма.
+// Аккаунт в статусе pending становится активным.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2
+	`, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := activatePending(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePassword сохраняет новый хеш пароля, снимает требование сменить пароль
+// и увеличивает поколение токенов пользователя. Возвращает новое поколение.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) (int, error) {
+	var generation int
 
 	query := `
-		SELECT id, email, password_hash, created_at, updated_at
-		FROM users 
+		UPDATE users
+		SET password_hash = $2, password_reset_required = FALSE, token_generation = token_generation + 1, updated_at = NOW()
 		WHERE id = $1
+		RETURNING token_generation
 	`
 
-	err := r.db.QueryRow(ctx, query, userID).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := r.db.QueryRow(ctx, query, userID, passwordHash).Scan(&generation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return generation, nil
+}
+
+// UpgradePasswordHash заменяет хеш того же пароля на хеш другой схемы. Поколение токенов
+// не меняется: пароль прежний. Если пароль успели сменить, ничего не делает.
+func (r *UserRepository) UpgradePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND password_hash = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, oldHash, newHash); err != nil {
+		return fmt.Errorf("failed to upgrade password hash: %w", err)
+	}
+
+	return nil
+}
 
+// UpdateEmail меняет адрес пользователя на подтвержденный и увеличивает поколение токенов,
+// чтобы токены со старым email перестали приниматься. Возвращает новое поколение.
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) (int, error) {
+	var generation int
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE users
+		SET email = $2, email_verified_at = NOW(), token_generation = token_generation + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING token_generation
+	`
+
+	err = tx.QueryRow(ctx, query, userID, email).Scan(&generation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return 0, ErrEmailTaken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update email: %w", err)
+	}
+
+	// Новый адрес подтвержден ссылкой из письма
+	if err := activatePending(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return generation, nil
+}
+
+// ScheduleDeletion помечает аккаунт удаленным и увеличивает поколение токенов,
+// чтобы все выданные токены перестали приниматься
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, purgeAfter time.Time, reason string) error {
+	query := statusTransitionQuery("$4",
+		", deleted_at = NOW(), purge_after = $5, token_generation = u.token_generation + 1",
+		" AND u.deleted_at IS NULL")
+
+	tag, err := r.db.Exec(ctx, query, userID, reason,
+		statusNames(models.StatusesTransitioningTo(models.UserStatusDeleted)), models.UserStatusDeleted, purgeAfter)
+	if err != nil {
+		return fmt.Errorf("failed to schedule user deletion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CancelDeletion отменяет удаление аккаунта, если срок еще не истек, и возвращает статус,
+// в котором аккаунт был до удаления: отмена удаления не снимает приостановку.
+func (r *UserRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) (models.UserStatus, error) {
+	var status models.UserStatus
+
+	query := statusTransitionQuery(`COALESCE(
+			(SELECT h.from_status FROM user_status_history h
+			WHERE h.user_id = u.id AND h.to_status = 'deleted'
+			ORDER BY h.created_at DESC LIMIT 1),
+			CASE WHEN u.email_verified_at IS NULL THEN 'pending' ELSE 'active' END)`,
+		", deleted_at = NULL, purge_after = NULL",
+		" AND u.deleted_at IS NOT NULL AND u.purge_after > NOW()")
+
+	err := r.db.QueryRow(ctx, query, userID, models.StatusReasonDeletionCancelled,
+		statusNames([]models.UserStatus{models.UserStatusDeleted})).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to cancel user deletion: %w", err)
+	}
+
+	return status, nil
+}
+
+// ChangeStatus переводит пользователя в статус status с причиной reason, если переход
+// из текущего статуса допустим. Удаление и его отмена выполняются только через
+// ScheduleDeletion и CancelDeletion. Приостановка увеличивает поколение токенов,
+// чтобы выданные токены перестали приниматься.
+func (r *UserRepository) ChangeStatus(ctx context.Context, userID uuid.UUID, status models.UserStatus, reason string) error {
+	if status == models.UserStatusDeleted {
+		return ErrStatusTransition
+	}
+
+	var from []models.UserStatus
+	for _, current := range models.StatusesTransitioningTo(status) {
+		if current != models.UserStatusDeleted {
+			from = append(from, current)
+		}
+	}
+
+	var set string
+	if status == models.UserStatusSuspended {
+		set = ", token_generation = u.token_generation + 1"
+	}
+	query := statusTransitionQuery("$4", set, "")
+
+	tag, err := r.db.Exec(ctx, query, userID, reason, statusNames(from), status)
+	if err != nil {
+		return fmt.Errorf("failed to change user status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStatusTransition
+	}
+
+	return nil
+}
+
+// activatePending переводит аккаунт из pending в active после подтверждения адреса
+func activatePending(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	_, err := tx.Exec(ctx, statusTransitionQuery("$4", "", ""), userID, models.StatusReasonEmailVerified,
+		statusNames([]models.UserStatus{models.UserStatusPending}), models.UserStatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to activate user: %w", err)
+	}
+	return nil
+}
+
+// statusTransitionQuery собирает запрос, который меняет статус пользователя $1 на status
+// с причиной $2, только если текущий статус входит в массив $3, и тем же запросом
+// записывает переход в user_status_history. set и where дополняют UPDATE
+// (таблица users доступна как u). Запрос возвращает новый статус, если переход выполнен.
+func statusTransitionQuery(status, set, where string) string {
+	return `
+		WITH prev AS (
+			SELECT id, status FROM users WHERE id = $1 FOR UPDATE
+		), updated AS (
+			UPDATE users u
+			SET status = ` + status + `, status_reason = $2, status_changed_at = NOW(), updated_at = NOW()` + set + `
+			FROM prev
+			WHERE u.id = prev.id AND prev.status = ANY($3::text[])` + where + `
+			RETURNING u.id, prev.status AS from_status, u.status AS to_status, u.status_reason, u.status_changed_at
+		)
+		INSERT INTO user_status_history (user_id, from_status, to_status, reason, created_at)
+		SELECT id, from_status, to_status, status_reason, status_changed_at FROM updated
+		RETURNING to_status
+	`
+}
+
+// statusNames преобразует статусы в строки для параметра-массива
+func statusNames(statuses []models.UserStatus) []string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	return names
+}
+
+// PurgeDeletedUsers окончательно удаляет аккаунты, срок отмены удаления которых истек.
+// Зависимые данные удаляются каскадно.
+func (r *UserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND purge_after <= $1`
+
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// UpdateProfile сохраняет профиль пользователя и возвращает обновленного пользователя
+func (r *UserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, profile *models.Profile) (*models.User, error) {
+	metadata := profile.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	query := `
+		UPDATE users
+		SET username = $2, display_name = $3, avatar_url = $4, locale = $5, timezone = $6, metadata = $7, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + userColumns
+
+	user, err := scanUser(r.db.QueryRow(ctx, query,
+		userID,
+		profile.Username,
+		profile.DisplayName,
+		profile.AvatarURL,
+		profile.Locale,
+		profile.Timezone,
+		metadata,
+	))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
+	if isUsernameTaken(err) {
+		return nil, ErrUsernameTaken
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return user, nil
+}
+
+// RecordFailedLogin учитывает неудачную попытку входа. Когда попыток набирается threshold,
+// счетчик обнуляется и вход блокируется на lockFor * 2^(число предыдущих блокировок),
+// но не дольше maxLockFor. Возвращает срок блокировки, если эта попытка ее включила.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, userID uuid.UUID, threshold int, lockFor, maxLockFor time.Duration) (*time.Time, error) {
+	var lockedUntil *time.Time
+	var locked bool
+
+	// Все выражения SET видят значения до обновления, поэтому блокировка атомарна
+	// и при параллельных попытках. failed_login_attempts = 0 после обновления
+	// означает, что порог достигнут именно этой попыткой.
+	query := `
+		UPDATE users
+		SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END,
+			lockout_count = CASE WHEN failed_login_attempts + 1 >= $2 THEN lockout_count + 1 ELSE lockout_count END,
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $2
+				THEN NOW() + LEAST($3::float8 * POWER(2, LEAST(lockout_count, 30)), $4::float8) * INTERVAL '1 second'
+				ELSE locked_until END
+		WHERE id = $1
+		RETURNING locked_until, failed_login_attempts = 0
+	`
+
+	err := r.db.QueryRow(ctx, query, userID, threshold, lockFor.Seconds(), maxLockFor.Seconds()).Scan(&lockedUntil, &locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
 	}
 
+	if !locked {
+		return nil, nil
+	}
+	return lockedUntil, nil
+}
+
+// ResetFailedLogins обнуляет счетчики неудачных входов и снимает блокировку
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = 0, lockout_count = 0, locked_until = NULL
+		WHERE id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTOTPSecret сохраняет зашифрованный секрет TOTP, ожидающий подтверждения.
+// Пока TOTP не включена, повторное подключение заменяет секрет.
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $2, updated_at = NOW()
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStateChanged
+	}
+
+	return nil
+}
+
+// EnableTOTP включает TOTP с секретом, первый код для которого подтвердил пользователь.
+// Если секрет успели заменить, возвращает ErrTOTPStateChanged.
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, secret string, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_enabled_at = NOW(), totp_last_used_step = $3, updated_at = NOW()
+		WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret = $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID, secret, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStateChanged
+	}
+
+	return nil
+}
+
+// DisableTOTP отключает TOTP и удаляет секрет
+func (r *UserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_used_step = 0, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UseTOTPStep запоминает период принятого кода TOTP. Возвращает false, если код
+// этого или более позднего периода уже использован: один код дважды не принимается.
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_used_step = $2
+		WHERE id = $1 AND totp_last_used_step < $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// ImportUsers вставляет пачку пользователей: строки копируются (COPY) во временную таблицу
+// и переносятся в users одним запросом. Пользователи, чей email или username уже заняты,
+// пропускаются. Возвращает id вставленных пользователей.
+func (r *UserRepository) ImportUsers(ctx context.Context, users []models.User) ([]uuid.UUID, error) {
+	columns := []string{"id", "email", "username", "password_hash", "email_verified_at", "status", "status_reason", "created_at"}
+	return r.copyUsers(ctx, users, columns, func(user *models.User) []any {
+		return []any{user.ID, user.Email, user.Username, user.PasswordHash, user.EmailVerifiedAt, user.Status, user.StatusReason, user.CreatedAt}
+	}, false)
+}
+
+// RestoreUsers вставляет пачку пользователей из резервной копии со всеми колонками.
+// Пользователи с уже существующим id, email или username пропускаются, поэтому повторное
+// восстановление ничего не меняет. С dryRun транзакция откатывается, и метод только
+// сообщает, какие пользователи были бы вставлены.
+func (r *UserRepository) RestoreUsers(ctx context.Context, users []models.User, dryRun bool) ([]uuid.UUID, error) {
+	return r.copyUsers(ctx, users, userColumnNames, userValues, dryRun)
+}
+
+// copyUsers копирует колонки пользователей во временную таблицу и вставляет в users
+// те строки, которые не конфликтуют с существующими. Возвращает id вставленных.
+func (r *UserRepository) copyUsers(ctx context.Context, users []models.User, columns []string, values func(user *models.User) []any, dryRun bool) ([]uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Незаполненные колонки получают значения по умолчанию из users
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE import_users (LIKE users INCLUDING DEFAULTS) ON COMMIT DROP`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_users"}, columns, pgx.CopyFromSlice(len(users), func(i int) ([]any, error) {
+		return values(&users[i]), nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy users: %w", err)
+	}
+
+	names := strings.Join(columns, ", ")
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		INSERT INTO users (%[1]s)
+		SELECT %[1]s FROM import_users
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to import users: %w", err)
+	}
+
+	inserted := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan imported user: %w", err)
+		}
+		inserted = append(inserted, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to import users: %w", err)
+	}
+
+	if dryRun {
+		return inserted, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// ExportUsers читает всех пользователей со всеми колонками в порядке регистрации
+// и передает каждого в fn. Выборка идет одним запросом, поэтому видит согласованный снимок.
+func (r *UserRepository) ExportUsers(ctx context.Context, fn func(user *models.User) error) error {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users ORDER BY created_at, id`)
+	if err != nil {
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsersByIDs возвращает найденных пользователей из списка id
+func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ListUsers возвращает пользователей, подходящих под фильтр, от новых к старым
+func (r *UserRepository) ListUsers(ctx context.Context, filter *models.UserListFilter) ([]models.User, error) {
+	var conditions []string
+	var args []any
+
+	addCondition := func(condition string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Status != "" {
+		addCondition("status = ?", filter.Status)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		addCondition("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		addCondition("created_at < ?", filter.CreatedTo)
+	}
+	if filter.EmailPrefix != "" {
+		addCondition(`email LIKE ? ESCAPE '\'`, escapeLike(filter.EmailPrefix)+"%")
+	}
+	if filter.After != nil {
+		addCondition("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ListStatusHistory возвращает историю переходов статуса пользователя от новых к старым
+func (r *UserRepository) ListStatusHistory(ctx context.Context, userID uuid.UUID) ([]models.UserStatusChange, error) {
+	query := `
+		SELECT id, user_id, from_status, to_status, reason, created_at
+		FROM user_status_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.UserStatusChange{}
+	for rows.Next() {
+		var change models.UserStatusChange
+		if err := rows.Scan(&change.ID, &change.UserID, &change.FromStatus, &change.ToStatus, &change.Reason, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// RevokeTokens увеличивает поколение токенов, чтобы все выданные access токены
+// перестали приниматься. Возвращает новое поколение.
+func (r *UserRepository) RevokeTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	var generation int
+
+	query := `
+		UPDATE users
+		SET token_generation = token_generation + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING token_generation
+	`
+
+	err := r.db.QueryRow(ctx, query, userID).Scan(&generation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	return generation, nil
+}
+
+// RequirePasswordReset запрещает вход по текущему паролю до его сброса
+// и отзывает выданные access токены
+func (r *UserRepository) RequirePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET password_reset_required = TRUE, token_generation = token_generation + 1, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to require password reset: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// GetTokenState возвращает текущее поколение токенов и статус пользователя и проверяет
+// наличие jti в denylist - все одним запросом, который выполняется на каждый запрос с токеном
+func (r *UserRepository) GetTokenState(ctx context.Context, userID, jti string) (int, models.UserStatus, bool, error) {
+	var generation int
+	var status models.UserStatus
+	var revoked bool
+
+	query := `
+		SELECT token_generation, status, EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $2)
+		FROM users
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(ctx, query, userID, jti).Scan(&generation, &status, &revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", false, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, "", false, fmt.Errorf("failed to get token state: %w", err)
+	}
+
+	return generation, status, revoked, nil
+}
+
+// userColumns - колонки, которые читает scanUser
+const userColumns = `id, email, password_hash, email_verified_at, token_generation, deleted_at, purge_after,
+	status, status_reason, status_changed_at, role, password_reset_required,
+	failed_login_attempts, lockout_count, locked_until,
+	totp_secret, totp_enabled_at, totp_last_used_step,
+	username, display_name, avatar_url, locale, timezone, metadata, created_at, updated_at`
+
+// userColumnNames - userColumns списком, для COPY
+var userColumnNames = strings.Fields(strings.ReplaceAll(userColumns, ",", " "))
+
+// userValues возвращает значения колонок пользователя в порядке userColumns
+func userValues(user *models.User) []any {
+	metadata := user.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	return []any{
+		user.ID,
+		user.Email,
+		nullIfEmpty(user.PasswordHash),
+		user.EmailVerifiedAt,
+		user.TokenGeneration,
+		user.DeletedAt,
+		user.PurgeAfter,
+		user.Status,
+		user.StatusReason,
+		user.StatusChangedAt,
+		user.Role,
+		user.PasswordResetRequired,
+		user.FailedLoginAttempts,
+		user.LockoutCount,
+		user.LockedUntil,
+		nullIfEmpty(user.TOTPSecret),
+		user.TOTPEnabledAt,
+		user.TOTPLastUsedStep,
+		user.Username,
+		user.DisplayName,
+		user.AvatarURL,
+		user.Locale,
+		user.Timezone,
+		metadata,
+		user.CreatedAt,
+		user.UpdatedAt,
+	}
+}
+
+// scanUser читает пользователя из строки, выбранной с userColumns
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	// У аккаунтов без пароля password_hash равен NULL, без TOTP - totp_secret
+	var passwordHash, totpSecret *string
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&passwordHash,
+		&user.EmailVerifiedAt,
+		&user.TokenGeneration,
+		&user.DeletedAt,
+		&user.PurgeAfter,
+		&user.Status,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.Role,
+		&user.PasswordResetRequired,
+		&user.FailedLoginAttempts,
+		&user.LockoutCount,
+		&user.LockedUntil,
+		&totpSecret,
+		&user.TOTPEnabledAt,
+		&user.TOTPLastUsedStep,
+		&user.Username,
+		&user.DisplayName,
+		&user.AvatarURL,
+		&user.Locale,
+		&user.Timezone,
+		&user.Metadata,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if passwordHash != nil {
+		user.PasswordHash = *passwordHash
+	}
+	if totpSecret != nil {
+		user.TOTPSecret = *totpSecret
+	}
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// nullIfEmpty возвращает nil для пустой строки, чтобы в колонку записался NULL
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
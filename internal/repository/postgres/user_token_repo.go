@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrUserTokenNotFound = errors.New("user token not found")
+)
+
+type UserTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenRepository(db *pgxpool.Pool) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// CreateUserToken сохраняет новый токен. Неиспользованные токены того же назначения
+// удаляются: действует только ссылка из последнего письма.
+func (r *UserTokenRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, token.UserID, token.Purpose)
+	if err != nil {
+		return fmt.Errorf("failed to delete previous user tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_tokens (id, user_id, purpose, token_hash, binding_hash, email, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+	`, token.ID, token.UserID, token.Purpose, token.TokenHash, token.BindingHash, token.Email, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit user token: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserToken возвращает неиспользованный токен, не помечая его использованным
+func (r *UserTokenRepository) GetUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+
+	query := `
+		SELECT id, user_id, purpose, token_hash, COALESCE(binding_hash, ''), email, expires_at, used_at, created_at
+		FROM user_tokens
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL
+	`
+
+	err := r.db.QueryRow(ctx, query, purpose, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.BindingHash,
+		&token.Email,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// ConsumeUserToken атомарно помечает токен использованным и возвращает его.
+// Повторное использование возвращает ErrUserTokenNotFound.
+func (r *UserTokenRepository) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	return r.ConsumeBoundUserToken(ctx, purpose, tokenHash, "")
+}
+
+// ConsumeBoundUserToken помечает использованным токен, привязанный к устройству.
+// Токен с другой привязкой не находится и остается действительным, чтобы по
+// пересланной ссылке нельзя было ни войти, ни сжечь ее. Пустой bindingHash
+// находит только непривязанные токены.
+func (r *UserTokenRepository) ConsumeBoundUserToken(ctx context.Context, purpose, tokenHash, bindingHash string) (*models.UserToken, error) {
+	var token models.UserToken
+
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL
+			AND binding_hash IS NOT DISTINCT FROM NULLIF($3, '')
+		RETURNING id, user_id, purpose, token_hash, COALESCE(binding_hash, ''), email, expires_at, used_at, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, purpose, tokenHash, bindingHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.BindingHash,
+		&token.Email,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume user token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// LastUserTokenCreatedAt возвращает время выдачи последнего токена указанного назначения
+func (r *UserTokenRepository) LastUserTokenCreatedAt(ctx context.Context, userID uuid.UUID, purpose string) (*time.Time, error) {
+	var createdAt *time.Time
+
+	query := `SELECT MAX(created_at) FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+	if err := r.db.QueryRow(ctx, query, userID, purpose).Scan(&createdAt); err != nil {
+		return nil, fmt.Errorf("failed to get last user token: %w", err)
+	}
+
+	return createdAt, nil
+}
+
+// DeleteUserTokens удаляет неиспользованные токены пользователя указанного назначения
+func (r *UserTokenRepository) DeleteUserTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	if _, err := r.db.Exec(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
+	}
+
+	return nil
+}
+
+// ListUserTokens возвращает все токены из писем пользователя, новые первыми
+func (r *UserTokenRepository) ListUserTokens(ctx context.Context, userID uuid.UUID) ([]models.UserToken, error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, email, expires_at, used_at, created_at
+		FROM user_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.UserToken{}
+	for rows.Next() {
+		var token models.UserToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Purpose,
+			&token.TokenHash,
+			&token.Email,
+			&token.ExpiresAt,
+			&token.UsedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user tokens: %w", err)
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// Ошибки удаления и смены статуса аккаунта
+var (
+	ErrAccountDeleted          = errors.New("account is scheduled for deletion")
+	ErrAccountSuspended        = errors.New("account is suspended")
+	ErrInvalidStatusTransition = errors.New("account status transition not allowed")
+	ErrStatusReasonRequired    = errors.New("status change reason is required")
+)
+
+// AccountService удаляет аккаунты: сначала аккаунт блокируется на срок отмены,
+// затем фоновая задача удаляет его окончательно вместе со всеми данными
+type AccountService struct {
+	userRepo      UserRepository
+	refreshRepo   RefreshTokenRepository
+	authenticator Authenticator
+	lockout       LoginLockout
+	codes         EmailCodeVerifier
+	users         UserCacheInvalidator
+	gracePeriod   time.Duration
+}
+
+func NewAccountService(userRepo UserRepository, refreshRepo RefreshTokenRepository, authenticator Authenticator, lockout LoginLockout, codes EmailCodeVerifier, users UserCacheInvalidator, gracePeriod time.Duration) *AccountService {
+	return &AccountService{
+		userRepo:      userRepo,
+		refreshRepo:   refreshRepo,
+		authenticator: authenticator,
+		lockout:       lockout,
+		codes:         codes,
+		users:         users,
+		gracePeriod:   gracePeriod,
+	}
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя после повторной проверки пароля
+// (в аккаунте без пароля - кода из письма). Возвращает момент окончательного удаления.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, password, code string) (time.Time, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := reauthenticate(ctx, s.lockout, s.users, s.codes, user, password, code, ErrWrongPassword); err != nil {
+		return time.Time{}, err
+	}
+
+	return s.ScheduleDeletion(ctx, user, models.StatusReasonDeletionRequested)
+}
+
+// ScheduleDeletion блокирует аккаунт и назначает окончательное удаление.
+// Все токены пользователя отзываются сразу, reason сохраняется в истории статусов.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, user *models.User, reason string) (time.Time, error) {
+	purgeAfter := time.Now().Add(s.gracePeriod)
+
+	if err := s.userRepo.ScheduleDeletion(ctx, user.ID, purgeAfter, reason); err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return time.Time{}, ErrAccountDeleted
+		}
+		return time.Time{}, err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	log.Printf("🗑️ Account %s scheduled for deletion after %s", user.ID, purgeAfter.Format(time.RFC3339))
+	return purgeAfter, nil
+}
+
+// RestoreAccount отменяет удаление по email и паролю, пока не истек срок отмены,
+// и завершает вход, как Login
+func (s *AccountService) RestoreAccount(ctx context.Context, email, password string) (*AuthResponse, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Кеш обходим: удаленный аккаунт в нем может отсутствовать или быть устаревшим
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// Для активного аккаунта ответ тот же, что и для неверного пароля: иначе восстановление
+	// подтверждало бы пароль в обход входа
+	if !user.IsDeleted() {
+		return nil, ErrInvalidCredentials
+	}
+	if err := checkPasswordWithLockout(ctx, s.lockout, s.users, user, password, ErrInvalidCredentials); err != nil {
+		return nil, err
+	}
+
+	status, err := s.userRepo.CancelDeletion(ctx, user.ID)
+	if err != nil {
+		// Срок отмены истек - аккаунт будет удален
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	user.DeletedAt = nil
+	user.PurgeAfter = nil
+	user.Status = status
+	s.users.InvalidateUser(user.Email)
+
+	log.Printf("♻️ Account %s restored", user.ID)
+
+	// Удаление отменено, но приостановленный аккаунт остается приостановленным
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	return s.authenticator.CompleteLogin(ctx, user)
+}
+
+// SuspendAccount приостанавливает аккаунт: вход запрещается, все выданные токены
+// перестают приниматься. Причина сохраняется в истории статусов.
+func (s *AccountService) SuspendAccount(ctx context.Context, userID, reason string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+
+	user, err = s.changeStatus(ctx, user, models.UserStatusSuspended, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("⛔ Account %s suspended: %s", user.ID, reason)
+	return user, nil
+}
+
+// UnsuspendAccount снимает приостановку. Токены, отозванные при приостановке, не возвращаются.
+func (s *AccountService) UnsuspendAccount(ctx context.Context, userID, reason string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsSuspended() {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	// Аккаунт с неподтвержденным адресом возвращается в pending
+	status := models.UserStatusActive
+	if !user.IsEmailVerified() {
+		status = models.UserStatusPending
+	}
+
+	user, err = s.changeStatus(ctx, user, status, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Account %s unsuspended: %s", user.ID, reason)
+	return user, nil
+}
+
+// changeStatus проверяет переход и переводит аккаунт в новый статус
+func (s *AccountService) changeStatus(ctx context.Context, user *models.User, status models.UserStatus, reason string) (*models.User, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrStatusReasonRequired
+	}
+	if !user.Status.CanTransitionTo(status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	if err := s.userRepo.ChangeStatus(ctx, user.ID, status, reason); err != nil {
+		// Статус успел измениться параллельно
+		if errors.Is(err, postgres.ErrStatusTransition) {
+			return nil, ErrInvalidStatusTransition
+		}
+		return nil, err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	return s.userRepo.GetUserByID(ctx, user.ID.String())
+}
+
+// PurgeDeletedAccounts окончательно удаляет аккаунты с истекшим сроком отмены
+func (s *AccountService) PurgeDeletedAccounts(ctx context.Context) error {
+	purged, err := s.userRepo.PurgeDeletedUsers(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("🧹 Purged %d deleted accounts", purged)
+	}
+	return nil
+}
+
+// StartPurging периодически удаляет аккаунты с истекшим сроком отмены, пока не отменен ctx
+func (s *AccountService) StartPurging(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.PurgeDeletedAccounts(ctx); err != nil {
+				log.Printf("❌ Failed to purge deleted accounts: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEmailCodeVerifier - мок проверки кодов из писем
+type MockEmailCodeVerifier struct {
+	mock.Mock
+}
+
+func (m *MockEmailCodeVerifier) VerifyCode(ctx context.Context, user *models.User, purpose, code string) error {
+	args := m.Called(ctx, user, purpose, code)
+	return args.Error(0)
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, mockRefreshRepo, new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("ScheduleDeletion", mock.Anything, user.ID, mock.MatchedBy(func(purgeAfter time.Time) bool {
+		return purgeAfter.After(time.Now().Add(23 * time.Hour))
+	}), models.StatusReasonDeletionRequested).Return(nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	purgeAfter, err := accountService.DeleteAccount(context.Background(), user.ID.String(), "password123", "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), purgeAfter, time.Minute)
+	mockUserRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestAccountService_DeleteAccount_WrongPassword(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), mockLockout, new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+
+	// Act
+	_, err := accountService.DeleteAccount(context.Background(), user.ID.String(), "wrong-password", "")
+
+	// Assert: неверный пароль учитывается в блокировке входа
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	mockLockout.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "ScheduleDeletion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_DeleteAccount_Locked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), mockLockout, new(MockEmailCodeVerifier), new(MockUserCacheInvalidator), 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	lockedUntil := time.Now().Add(time.Hour)
+	user.LockedUntil = &lockedUntil
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+	// Act: во время блокировки не принимается даже верный пароль
+	_, err := accountService.DeleteAccount(context.Background(), user.ID.String(), "password123", "")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	mockLockout.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "ScheduleDeletion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_DeleteAccount_PasswordlessConfirmsWithCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockCodes := new(MockEmailCodeVerifier)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, mockRefreshRepo, new(MockAuthenticator), new(MockLoginLockout), mockCodes, mockUsers, 24*time.Hour)
+
+	// Аккаунт создан входом по ссылке из письма и пароля не имеет
+	user := newUnverifiedUser(t)
+	user.PasswordHash = ""
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockCodes.On("VerifyCode", mock.Anything, user, models.EmailOTPReauth, "000000").Return(ErrInvalidOTP)
+	mockCodes.On("VerifyCode", mock.Anything, user, models.EmailOTPReauth, "123456").Return(nil)
+	mockUserRepo.On("ScheduleDeletion", mock.Anything, user.ID, mock.Anything, models.StatusReasonDeletionRequested).Return(nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act & Assert: без кода и с неверным кодом аккаунт не удаляется
+	_, err := accountService.DeleteAccount(context.Background(), user.ID.String(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+	_, err = accountService.DeleteAccount(context.Background(), user.ID.String(), "", "000000")
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+	mockUserRepo.AssertNotCalled(t, "ScheduleDeletion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = accountService.DeleteAccount(context.Background(), user.ID.String(), "", "123456")
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_DeletedAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	authService := NewAuthService(mockUserRepo, mockJWTService, new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	deletedAt := time.Now()
+	user.DeletedAt = &deletedAt
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountDeleted)
+	assert.Nil(t, result)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_RestoreAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), mockAuthenticator, new(MockLoginLockout), new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	deletedAt := time.Now()
+	purgeAfter := deletedAt.Add(24 * time.Hour)
+	user.DeletedAt = &deletedAt
+	user.PurgeAfter = &purgeAfter
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("CancelDeletion", mock.Anything, user.ID).Return(models.UserStatusActive, nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockAuthenticator.On("CompleteLogin", mock.Anything, user).Return(&AuthResponse{User: user, Token: "access"}, nil)
+
+	// Act
+	result, err := accountService.RestoreAccount(context.Background(), user.Email, "password123")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "access", result.Token)
+	assert.False(t, user.IsDeleted())
+	mockUserRepo.AssertExpectations(t)
+	mockAuthenticator.AssertExpectations(t)
+}
+
+func TestAccountService_RestoreAccount_GracePeriodExpired(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), mockAuthenticator, new(MockLoginLockout), new(MockEmailCodeVerifier), new(MockUserCacheInvalidator), 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	user.DeletedAt = &deletedAt
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("CancelDeletion", mock.Anything, user.ID).Return(models.UserStatus(""), postgres.ErrUserNotFound)
+
+	// Act
+	result, err := accountService.RestoreAccount(context.Background(), user.Email, "password123")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Nil(t, result)
+	mockAuthenticator.AssertNotCalled(t, "CompleteLogin", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_RestoreAccount_SameErrorForEveryFailure(t *testing.T) {
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		deleted   bool
+		password  string
+		wantCheck bool
+	}{
+		// Пароль активного аккаунта не проверяется: иначе восстановление подтверждало бы его в обход входа
+		{"account not deleted", false, "password123", false},
+		{"wrong password", true, "wrong-password", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUserRepo := new(MockUserRepository)
+			mockLockout := new(MockLoginLockout)
+			mockUsers := new(MockUserCacheInvalidator)
+			accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), mockLockout, new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+			user := newUnverifiedUser(t)
+			if tt.deleted {
+				user.DeletedAt = &deletedAt
+			}
+			mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+			mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil)
+			mockUsers.On("InvalidateUser", user.Email).Return()
+
+			// Act
+			result, err := accountService.RestoreAccount(context.Background(), user.Email, tt.password)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+			assert.Nil(t, result)
+			if tt.wantCheck {
+				mockLockout.AssertCalled(t, "RecordFailure", mock.Anything, user)
+			} else {
+				mockLockout.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+			}
+			mockUserRepo.AssertNotCalled(t, "CancelDeletion", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAccountService_RestoreAccount_Locked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), new(MockUserCacheInvalidator), 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	deletedAt := time.Now()
+	lockedUntil := deletedAt.Add(time.Hour)
+	user.DeletedAt = &deletedAt
+	user.LockedUntil = &lockedUntil
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act
+	result, err := accountService.RestoreAccount(context.Background(), user.Email, "password123")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.Nil(t, result)
+	mockUserRepo.AssertNotCalled(t, "CancelDeletion", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_SuspendAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, mockRefreshRepo, new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	user.Status = models.UserStatusActive
+	suspended := *user
+	suspended.Status = models.UserStatusSuspended
+
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil).Once()
+	mockUserRepo.On("ChangeStatus", mock.Anything, user.ID, models.UserStatusSuspended, "spam").Return(nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(&suspended, nil).Once()
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	result, err := accountService.SuspendAccount(context.Background(), user.ID.String(), "  spam ")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, models.UserStatusSuspended, result.Status)
+	mockUserRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestAccountService_SuspendAccount_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  models.UserStatus
+		reason  string
+		wantErr error
+	}{
+		{"reason required", models.UserStatusActive, " ", ErrStatusReasonRequired},
+		{"already suspended", models.UserStatusSuspended, "spam", ErrInvalidStatusTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUserRepo := new(MockUserRepository)
+			accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), new(MockUserCacheInvalidator), 24*time.Hour)
+
+			user := newUnverifiedUser(t)
+			user.Status = tt.status
+			mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+			// Act
+			_, err := accountService.SuspendAccount(context.Background(), user.ID.String(), tt.reason)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockUserRepo.AssertNotCalled(t, "ChangeStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAccountService_UnsuspendAccount_UnverifiedReturnsToPending(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	user.Status = models.UserStatusSuspended
+
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("ChangeStatus", mock.Anything, user.ID, models.UserStatusPending, "appeal accepted").Return(nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+
+	// Act
+	_, err := accountService.UnsuspendAccount(context.Background(), user.ID.String(), "appeal accepted")
+
+	// Assert
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAccountService_RestoreAccount_Suspended(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	mockUsers := new(MockUserCacheInvalidator)
+	accountService := NewAccountService(mockUserRepo, new(MockRefreshTokenRepository), mockAuthenticator, new(MockLoginLockout), new(MockEmailCodeVerifier), mockUsers, 24*time.Hour)
+
+	user := newUnverifiedUser(t)
+	deletedAt := time.Now()
+	user.DeletedAt = &deletedAt
+	user.Status = models.UserStatusDeleted
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("CancelDeletion", mock.Anything, user.ID).Return(models.UserStatusSuspended, nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+
+	// Act: удаление отменяется, но приостановка остается
+	result, err := accountService.RestoreAccount(context.Background(), user.Email, "password123")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+	assert.Nil(t, result)
+	mockAuthenticator.AssertNotCalled(t, "CompleteLogin", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_SuspendedAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	user.Status = models.UserStatusSuspended
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+	assert.Nil(t, result)
+}
+
+func TestUserStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to models.UserStatus
+		allowed  bool
+	}{
+		{models.UserStatusPending, models.UserStatusActive, true},
+		{models.UserStatusActive, models.UserStatusSuspended, true},
+		{models.UserStatusSuspended, models.UserStatusActive, true},
+		{models.UserStatusDeleted, models.UserStatusSuspended, true},
+		{models.UserStatusActive, models.UserStatusPending, false},
+		{models.UserStatusSuspended, models.UserStatusSuspended, false},
+		{models.UserStatus("unknown"), models.UserStatusActive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor - курсор списка пользователей поврежден или подделан
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Размер страницы списка пользователей по умолчанию
+const defaultUserPageSize = 50
+
+// UserPage - страница списка пользователей; NextCursor пуст на последней странице
+type UserPage struct {
+	Users      []models.User `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// UserDetails - пользователь вместе с историей переходов статуса
+type UserDetails struct {
+	User          *models.User              `json:"user"`
+	StatusHistory []models.UserStatusChange `json:"status_history"`
+}
+
+// AdminService - операции поддержки над аккаунтами пользователей
+type AdminService struct {
+	userRepo    UserRepository
+	refreshRepo RefreshTokenRepository
+	accounts    AccountManager
+	passwords   PasswordResetSender
+	lockout     LoginLockout
+	users       UserCacheInvalidator
+}
+
+func NewAdminService(userRepo UserRepository, refreshRepo RefreshTokenRepository, accounts AccountManager, passwords PasswordResetSender, lockout LoginLockout, users UserCacheInvalidator) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		accounts:    accounts,
+		passwords:   passwords,
+		lockout:     lockout,
+		users:       users,
+	}
+}
+
+// IsAdmin сообщает, есть ли у субъекта токена доступ к /admin: у сервиса - scope admin,
+// у пользователя - еще и роль admin на момент запроса. Токен, полученный пользователем
+// через OAuth клиента, scope admin не содержит, поэтому доступа не дает.
+func (s *AdminService) IsAdmin(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if !claims.HasScope(auth.ScopeAdmin) {
+		return false, nil
+	}
+	if claims.Principal() == auth.PrincipalService {
+		return true, nil
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return user.IsAdmin(), nil
+}
+
+// ListUsers возвращает страницу пользователей от новых к старым
+func (s *AdminService) ListUsers(ctx context.Context, req *models.ListUsersRequest) (*UserPage, error) {
+	filter := &models.UserListFilter{
+		Status:      models.UserStatus(req.Status),
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		// Адреса хранятся нормализованными, поэтому префикс приводится к нижнему регистру
+		EmailPrefix: strings.ToLower(strings.TrimSpace(req.Email)),
+		Limit:       req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if req.Cursor != "" {
+		after, err := decodeUserCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// Лишняя запись показывает, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+
+	users, err := s.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		last := page.Users[limit-1]
+		page.NextCursor = encodeUserCursor(&models.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// GetUser возвращает пользователя с историей статусов
+func (s *AdminService) GetUser(ctx context.Context, userID string) (*UserDetails, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.userRepo.ListStatusHistory(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserDetails{User: user, StatusHistory: history}, nil
+}
+
+// SuspendUser приостанавливает аккаунт
+func (s *AdminService) SuspendUser(ctx context.Context, userID, reason string) (*models.User, error) {
+	return s.accounts.SuspendAccount(ctx, userID, reason)
+}
+
+// UnsuspendUser снимает приостановку аккаунта
+func (s *AdminService) UnsuspendUser(ctx context.Context, userID, reason string) (*models.User, error) {
+	return s.accounts.UnsuspendAccount(ctx, userID, reason)
+}
+
+// ForceLogout завершает все сессии пользователя: access токены перестают приниматься,
+// refresh токены отзываются
+func (s *AdminService) ForceLogout(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.userRepo.RevokeTokens(ctx, user.ID); err != nil {
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	log.Printf("🚪 All sessions of user %s revoked by admin", user.ID)
+	return nil
+}
+
+// ForcePasswordReset запрещает вход по текущему паролю, завершает все сессии
+// и отправляет пользователю ссылку для сброса пароля
+func (s *AdminService) ForcePasswordReset(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted() {
+		return ErrAccountDeleted
+	}
+
+	if err := s.userRepo.RequirePasswordReset(ctx, user.ID); err != nil {
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	log.Printf("🔑 Password reset required for user %s by admin", user.ID)
+	return s.passwords.SendPasswordReset(ctx, user)
+}
+
+// DeleteUser назначает удаление аккаунта с обычным сроком отмены
+func (s *AdminService) DeleteUser(ctx context.Context, userID, reason string) (time.Time, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return time.Time{}, ErrStatusReasonRequired
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.accounts.ScheduleDeletion(ctx, user, reason)
+}
+
+// UnlockAccount снимает блокировку входа после неудачных попыток
+func (s *AdminService) UnlockAccount(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.lockout.Reset(ctx, user); err != nil {
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	log.Printf("🔓 Account %s unlocked", user.ID)
+	return nil
+}
+
+// encodeUserCursor кодирует позицию в списке пользователей в непрозрачную строку
+func encodeUserCursor(cursor *models.UserCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeUserCursor разбирает курсор, выданный encodeUserCursor
+func decodeUserCursor(value string) (*models.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &models.UserCursor{}
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
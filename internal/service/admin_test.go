@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPasswordResetSender - мок отправки ссылки для сброса пароля
+type MockPasswordResetSender struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetSender) SendPasswordReset(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func newTestAdminService(userRepo *MockUserRepository, refreshRepo *MockRefreshTokenRepository, passwords *MockPasswordResetSender, users *MockUserCacheInvalidator) *AdminService {
+	accounts := NewAccountService(userRepo, refreshRepo, new(MockAuthenticator), new(MockLoginLockout), new(MockEmailCodeVerifier), users, 24*time.Hour)
+	return NewAdminService(userRepo, refreshRepo, accounts, passwords, new(MockLoginLockout), users)
+}
+
+func TestAdminService_ListUsers_Pagination(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	adminService := newTestAdminService(mockUserRepo, new(MockRefreshTokenRepository), new(MockPasswordResetSender), new(MockUserCacheInvalidator))
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	users := []models.User{
+		{ID: uuid.New(), Email: "a@example.com", CreatedAt: createdAt.Add(time.Minute)},
+		{ID: uuid.New(), Email: "b@example.com", CreatedAt: createdAt},
+		{ID: uuid.New(), Email: "c@example.com", CreatedAt: createdAt.Add(-time.Minute)},
+	}
+
+	// Первая страница: запрашивается на одну запись больше лимита
+	mockUserRepo.On("ListUsers", mock.Anything, mock.MatchedBy(func(filter *models.UserListFilter) bool {
+		return filter.After == nil && filter.Limit == 3 && filter.Status == models.UserStatusActive && filter.EmailPrefix == "john"
+	})).Return(users, nil).Once()
+
+	// Act
+	page, err := adminService.ListUsers(context.Background(), &models.ListUsersRequest{Status: "active", Email: " John", Limit: 2})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	// Курсор указывает на последнего пользователя страницы
+	mockUserRepo.On("ListUsers", mock.Anything, mock.MatchedBy(func(filter *models.UserListFilter) bool {
+		return filter.After != nil && filter.After.ID == users[1].ID && filter.After.CreatedAt.Equal(createdAt)
+	})).Return(users[2:], nil).Once()
+
+	page, err = adminService.ListUsers(context.Background(), &models.ListUsersRequest{Cursor: page.NextCursor, Limit: 2})
+
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Empty(t, page.NextCursor)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAdminService_ListUsers_InvalidCursor(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	adminService := newTestAdminService(mockUserRepo, new(MockRefreshTokenRepository), new(MockPasswordResetSender), new(MockUserCacheInvalidator))
+
+	_, err := adminService.ListUsers(context.Background(), &models.ListUsersRequest{Cursor: "not-a-cursor"})
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	mockUserRepo.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything)
+}
+
+func TestAdminService_ForcePasswordReset(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockPasswords := new(MockPasswordResetSender)
+	mockUsers := new(MockUserCacheInvalidator)
+	adminService := newTestAdminService(mockUserRepo, mockRefreshRepo, mockPasswords, mockUsers)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("RequirePasswordReset", mock.Anything, user.ID).Return(nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+	mockPasswords.On("SendPasswordReset", mock.Anything, user).Return(nil)
+
+	// Act
+	err := adminService.ForcePasswordReset(context.Background(), user.ID.String())
+
+	// Assert
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+	mockPasswords.AssertExpectations(t)
+}
+
+func TestAdminService_ForceLogout(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	adminService := newTestAdminService(mockUserRepo, mockRefreshRepo, new(MockPasswordResetSender), mockUsers)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("RevokeTokens", mock.Anything, user.ID).Return(3, nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	err := adminService.ForceLogout(context.Background(), user.ID.String())
+
+	// Assert
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAdminService_DeleteUser_RecordsReason(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	adminService := newTestAdminService(mockUserRepo, mockRefreshRepo, new(MockPasswordResetSender), mockUsers)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("ScheduleDeletion", mock.Anything, user.ID, mock.AnythingOfType("time.Time"), "gdpr request").Return(nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	_, err := adminService.DeleteUser(context.Background(), user.ID.String(), " gdpr request ")
+
+	// Assert
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAdminService_IsAdmin(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	adminService := newTestAdminService(mockUserRepo, new(MockRefreshTokenRepository), new(MockPasswordResetSender), new(MockUserCacheInvalidator))
+
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin}
+	regular := &models.User{ID: uuid.New(), Role: models.RoleUser}
+	mockUserRepo.On("GetUserByID", mock.Anything, admin.ID.String()).Return(admin, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, regular.ID.String()).Return(regular, nil)
+
+	tests := []struct {
+		name    string
+		claims  *auth.Claims
+		isAdmin bool
+	}{
+		{"admin user", &auth.Claims{UserID: admin.ID.String(), Scope: "profile admin"}, true},
+		// Токен, выданный администратору OAuth клиентом, не содержит scope admin
+		{"admin user without admin scope", &auth.Claims{UserID: admin.ID.String(), Scope: auth.ScopeProfile}, false},
+		{"regular user", &auth.Claims{UserID: regular.ID.String()}, false},
+		// Scope admin у пользовательского токена не дает доступа без роли
+		{"regular user with admin scope", &auth.Claims{UserID: regular.ID.String(), Scope: auth.ScopeAdmin}, false},
+		{"service with admin scope", &auth.Claims{ClientID: "support-tool", Scope: auth.ScopeAdmin}, true},
+		{"service without admin scope", &auth.Claims{ClientID: "billing", Scope: auth.ScopeProfile}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			isAdmin, err := adminService.IsAdmin(context.Background(), tt.claims)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.isAdmin, isAdmin)
+		})
+	}
+}
+
+func TestAuthService_Login_PasswordResetRequired(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	user.PasswordResetRequired = true
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrPasswordResetRequired)
+	assert.Nil(t, result)
+}
@@ -4,37 +4,94 @@ import (
 	"context"
 	"errors"
 	"log"
+	"slices"
+	"strings"
 	"time"
 
+	"auth-service/internal/auth"
 	"auth-service/internal/cache"
 	"auth-service/internal/email"
 	"auth-service/internal/models"
 	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Ошибки аутентификации
+var (
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	// ErrPasswordResetRequired - администратор потребовал сменить пароль, вход по текущему запрещен
+	ErrPasswordResetRequired = errors.New("password reset required")
+)
+
+// Ошибки смены пароля
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrPasswordUnchanged = errors.New("new password must differ from the current one")
+	ErrPasswordTooLong   = errors.New("password must not exceed 72 bytes")
+)
+
+// maxPasswordBytes - bcrypt учитывает только первые 72 байта пароля
+const maxPasswordBytes = 72
+
+// firstPartyScopes выдаются токенам, полученным через /auth/register и /auth/login
+var firstPartyScopes = []string{auth.ScopeProfile, auth.ScopeAccount}
+
+// firstPartyScopesFor возвращает scopes входа первой стороны для пользователя:
+// администратор дополнительно получает scope admin. Через OAuth клиентов он не выдается.
+func firstPartyScopesFor(user *models.User) []string {
+	if user.IsAdmin() {
+		return append(slices.Clone(firstPartyScopes), auth.ScopeAdmin)
+	}
+	return firstPartyScopes
+}
+
 type AuthService struct {
-	userRepo     UserRepository
-	jwtService   JWTService
-	userCache    *cache.UserCache
-	emailService *email.EmailService
+	userRepo           UserRepository
+	refreshRepo        RefreshTokenRepository
+	jwtService         JWTService
+	revoker            TokenRevoker
+	verifier           EmailVerifier
+	lockout            LoginLockout
+	mfa                MFAChallenger
+	verificationPolicy VerificationPolicy
+	refreshExpiration  time.Duration
+	userCache          *cache.UserCache
+	emailService       *email.EmailService
 }
 
-func NewAuthService(userRepo UserRepository, jwtService JWTService) *AuthService {
+func NewAuthService(userRepo UserRepository, jwtService JWTService, refreshRepo RefreshTokenRepository, revoker TokenRevoker, verifier EmailVerifier, lockout LoginLockout, mfa MFAChallenger, verificationPolicy VerificationPolicy, refreshExpiration time.Duration) *AuthService {
 	return &AuthService{
-		userRepo:     userRepo,
-		jwtService:   jwtService,
-		userCache:    cache.NewUserCache(5 * time.Minute),
-		emailService: email.NewEmailService(),
+		userRepo:           userRepo,
+		refreshRepo:        refreshRepo,
+		jwtService:         jwtService,
+		revoker:            revoker,
+		verifier:           verifier,
+		lockout:            lockout,
+		mfa:                mfa,
+		verificationPolicy: verificationPolicy,
+		refreshExpiration:  refreshExpiration,
+		userCache:          cache.NewUserCache(5 * time.Minute),
+		emailService:       email.NewEmailService(),
 	}
 }
 
 type AuthResponse struct {
-	User  *models.User `json:"user"`
-	Token string       `json:"token"`
+	User                  *models.User `json:"user"`
+	Token                 string       `json:"token"`
+	TokenExpiresAt        time.Time    `json:"token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	Scope                 string       `json:"scope,omitempty"`
+	// MFAToken выдается вместо токенов, если у пользователя включена TOTP
+	MFAToken          string     `json:"mfa_token,omitempty"`
+	MFATokenExpiresAt *time.Time `json:"mfa_token_expires_at,omitempty"`
 }
 
-// GetUserByEmail с кешированием
+// GetUserByEmail с кешированием. Адрес должен быть нормализован (NormalizeEmail),
+// иначе кеш и БД не найдут пользователя
 func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	// Пытаемся получить из кеша
 	if cachedUser := s.userCache.Get(email); cachedUser != nil {
@@ -47,16 +104,42 @@ func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*models
 		return nil, err
 	}
 
-	// Сохраняем в кеш
+	// Сохраняем в кеш под хранимым адресом: по нему кеш и сбрасывается. Он отличается
+	// от запрошенного, пока адрес с доменом IDN не переписан в punycode
 	if user != nil {
-		s.userCache.Set(email, user)
+		s.userCache.Set(user.Email, user)
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername с кешированием
+func (s *AuthService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if cachedUser := s.userCache.GetByUsername(username); cachedUser != nil {
+		return cachedUser, nil
 	}
 
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	s.userCache.Set(user.Email, user)
+
 	return user, nil
 }
 
+// InvalidateUser удаляет пользователя из кеша после изменения его данных
+func (s *AuthService) InvalidateUser(email string) {
+	s.userCache.Delete(email)
+}
+
 // hashPassword хеширует пароль
 func hashPassword(password string) (string, error) {
+	if len(password) > maxPasswordBytes {
+		return "", ErrPasswordTooLong
+	}
+
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -64,13 +147,29 @@ func hashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-// checkPassword проверяет пароль
+// errPasswordMismatch - пароль не совпал с хешем
+var errPasswordMismatch = errors.New("password does not match")
+
+// checkPassword проверяет пароль по хешу bcrypt или импортированному хешу другой схемы
 func checkPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	_, ok, err := auth.VerifyPassword(hashedPassword, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errPasswordMismatch
+	}
+	return nil
 }
 
 // Register регистрирует нового пользователя
 func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest) (*AuthResponse, error) {
+	email, err := NormalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	req.Email = email
+
 	// Проверяем что пользователь с таким email не существует
 	exists, err := s.userRepo.UserExists(ctx, req.Email)
 	if err != nil {
@@ -80,25 +179,42 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 		return nil, errors.New("user with this email already exists")
 	}
 
-	// Хешируем пароль
-	passwordHash, err := hashPassword(req.Password)
-	if err != nil {
-		return nil, errors.New("failed to hash password")
+	if req.Username != "" {
+		if err := ValidateUsername(req.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	// Хешируем пароль; без пароля создается аккаунт для входа по ссылке из письма
+	var passwordHash string
+	if req.Password != "" {
+		passwordHash, err = hashPassword(req.Password)
+		if err != nil {
+			if errors.Is(err, ErrPasswordTooLong) {
+				return nil, err
+			}
+			return nil, errors.New("failed to hash password")
+		}
 	}
 
 	// Создаем пользователя
 	user, err := s.userRepo.CreateUser(ctx, req, passwordHash)
 	if err != nil {
+		if errors.Is(err, postgres.ErrUsernameTaken) {
+			return nil, ErrUsernameTaken
+		}
 		return nil, err
 	}
 
 	// Инвалидируем кеш при создании нового пользователя
 	s.userCache.Delete(req.Email)
 
-	// Генерируем JWT токен
-	token, err := s.jwtService.GenerateToken(user.ID.String(), user.Email)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
+	// Отправляем ссылку для подтверждения адреса; ошибка отправки не отменяет регистрацию,
+	// письмо можно запросить повторно
+	if s.verificationPolicy != VerificationPolicyOff {
+		if err := s.verifier.SendVerification(ctx, user); err != nil {
+			log.Printf("❌ Failed to send verification email to %s: %v", user.Email, err)
+		}
 	}
 
 	// 🔥 ЗАПУСКАЕМ ФОНОВУЮ ОТПРАВКУ EMAIL
@@ -106,38 +222,407 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 
 	log.Printf("🚀 Welcome email sending started in background for: %s", user.Email)
 
-	return &AuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
+	// Пока адрес не подтвержден, войти нельзя - токены не выдаем.
+	// Аккаунт без пароля входит только по ссылке из письма (/auth/magic-link).
+	if s.verificationPolicy == VerificationPolicyBlock || req.Password == "" {
+		return &AuthResponse{User: user}, nil
+	}
+
+	// Выдаем access и refresh токены
+	return s.IssueTokens(ctx, user, firstPartyScopesFor(user))
 }
 
 // Login выполняет вход пользователя
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*AuthResponse, error) {
-	// Используем кешированный метод
-	user, err := s.GetUserByEmail(ctx, req.Email)
+	user, err := s.Authenticate(ctx, req.Login(), req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CompleteLogin(ctx, user)
+}
+
+// CompleteLogin завершает вход пользователя, подтвердившего первый фактор: выдает
+// access и refresh токены либо, если включена TOTP, MFA токен для второго шага
+func (s *AuthService) CompleteLogin(ctx context.Context, user *models.User) (*AuthResponse, error) {
+	if !user.TOTPEnabled() {
+		return s.IssueTokens(ctx, user, firstPartyScopesFor(user))
+	}
+
+	mfaToken, expiresAt, err := s.mfa.Challenge(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔐 MFA challenge issued for user %s", user.ID)
+	return &AuthResponse{User: user, MFAToken: mfaToken, MFATokenExpiresAt: &expiresAt}, nil
+}
+
+// VerifyMFA - второй шаг входа: обменивает MFA токен и код из приложения на пару токенов
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string) (*AuthResponse, error) {
+	user, err := s.mfa.VerifyChallenge(ctx, mfaToken, code)
+	if err != nil {
+		return nil, err
+	}
+	// Проверка кода могла обнулить счетчик неудачных попыток
+	s.userCache.Delete(user.Email)
+
+	if user, err = s.checkLoginAllowed(ctx, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔓 MFA login completed for user %s", user.ID)
+	return s.IssueTokens(ctx, user, firstPartyScopesFor(user))
+}
+
+// Authenticate проверяет логин (email или username) и пароль и возвращает пользователя
+func (s *AuthService) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	// Используем кешированные методы
+	var user *models.User
+	var err error
+	if isEmailLogin(login) {
+		email, normalizeErr := NormalizeEmail(login)
+		if normalizeErr != nil {
+			return nil, ErrInvalidCredentials
+		}
+		user, err = s.GetUserByEmail(ctx, email)
+	} else {
+		user, err = s.GetUserByUsername(ctx, login)
+	}
 	if err != nil {
 		if errors.Is(err, postgres.ErrUserNotFound) {
-			return nil, errors.New("invalid email or password")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
 
+	// Аккаунт без пароля входит только по ссылке из письма. Попытка не учитывается
+	// в блокировке: блокировать нечего, а письмо о блокировке было бы спамом.
+	if !user.HasPassword() {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Во время блокировки пароль не проверяем, чтобы подбор не продолжался
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
 	// Проверяем пароль
-	if err := checkPassword(user.PasswordHash, req.Password); err != nil {
-		return nil, errors.New("invalid email or password")
+	if err := checkPassword(user.PasswordHash, password); err != nil {
+		return nil, s.recordFailedLogin(ctx, user)
+	}
+
+	// Успешный вход обнуляет счетчик неудачных попыток. С включенной TOTP счетчик
+	// обнуляет только верный код, иначе знающий пароль мог бы подбирать код бесконечно
+	if !user.TOTPEnabled() && (user.FailedLoginAttempts > 0 || user.LockoutCount > 0) {
+		if err := s.lockout.Reset(ctx, user); err != nil {
+			return nil, err
+		}
+		s.userCache.Delete(user.Email)
+	}
+	s.upgradePasswordHash(ctx, user, password)
+
+	return s.checkLoginAllowed(ctx, user)
+}
+
+// checkLoginAllowed проверяет, разрешен ли вход пользователю, подтвердившему учетные
+// данные. Вызывается и после пароля, и после второго шага с TOTP: между шагами
+// аккаунт могли удалить, приостановить или потребовать сменить пароль.
+// Возвращает пользователя, перечитанного из БД, если кеш мог устареть.
+func (s *AuthService) checkLoginAllowed(ctx context.Context, user *models.User) (*models.User, error) {
+	// Вход в удаленный аккаунт запрещен; отменить удаление можно через /auth/account/restore
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
+
+	if s.verificationPolicy != VerificationPolicyOff && !user.IsEmailVerified() {
+		// Кеш мог устареть, если пользователь только что перешел по ссылке из письма
+		reloaded, err := s.reloadUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		user = reloaded
+		if s.verificationPolicy == VerificationPolicyBlock && !user.IsEmailVerified() {
+			return nil, ErrEmailNotVerified
+		}
+	}
+
+	return user, nil
+}
+
+// recordFailedLogin учитывает неудачную попытку входа и возвращает ошибку для ответа
+func (s *AuthService) recordFailedLogin(ctx context.Context, user *models.User) error {
+	locked, err := s.lockout.RecordFailure(ctx, user)
+	// Счетчики в БД изменились - в кеше пользователь устарел
+	s.userCache.Delete(user.Email)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrAccountLocked
+	}
+	return ErrInvalidCredentials
+}
+
+// upgradePasswordHash заменяет импортированный хеш другой схемы на bcrypt после
+// успешного входа. Ошибка не мешает входу: хеш будет заменен при следующем.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	scheme, err := auth.PasswordHashScheme(user.PasswordHash)
+	if err != nil || scheme == auth.HashSchemeBcrypt {
+		return
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("⚠️ Failed to upgrade %s password hash of user %s: %v", scheme, user.ID, err)
+		return
+	}
+	if err := s.userRepo.UpgradePasswordHash(ctx, user.ID, user.PasswordHash, passwordHash); err != nil {
+		log.Printf("⚠️ Failed to upgrade %s password hash of user %s: %v", scheme, user.ID, err)
+		return
 	}
+	s.userCache.Delete(user.Email)
+
+	log.Printf("🔐 Password hash of user %s upgraded from %s to bcrypt", user.ID, scheme)
+}
+
+// reloadUser перечитывает пользователя из БД и обновляет кеш
+func (s *AuthService) reloadUser(ctx context.Context, user *models.User) (*models.User, error) {
+	fresh, err := s.userRepo.GetUserByID(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.userCache.Set(fresh.Email, fresh)
+	return fresh, nil
+}
+
+// IssueTokens выдает пару access/refresh токенов первой стороны и открывает новое
+// семейство refresh токенов. Scopes сохраняются вместе с refresh токеном и переходят
+// к токенам, полученным ротацией.
+func (s *AuthService) IssueTokens(ctx context.Context, user *models.User, scopes []string) (*AuthResponse, error) {
+	return s.issueTokens(ctx, user, "", scopes)
+}
+
+// IssueClientTokens - IssueTokens для OAuth клиента: обменять refresh токен
+// сможет только этот клиент
+func (s *AuthService) IssueClientTokens(ctx context.Context, user *models.User, clientID string, scopes []string) (*AuthResponse, error) {
+	return s.issueTokens(ctx, user, clientID, scopes)
+}
+
+// issueTokens открывает семейство refresh токенов клиента clientID (пустой - первая сторона)
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, clientID string, scopes []string) (*AuthResponse, error) {
+	authResponse, refreshToken, err := s.newTokenPair(user, uuid.New(), clientID, strings.Join(scopes, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshRepo.CreateRefreshToken(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	return authResponse, nil
+}
+
+// Refresh обменивает refresh токен первой стороны на новую пару токенов (ротация).
+// Повторное использование уже обмененного токена отзывает все семейство.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	return s.refresh(ctx, "", refreshToken)
+}
+
+// RefreshClient - Refresh для OAuth клиента, прошедшего аутентификацию:
+// принимаются только токены, выданные этому клиенту
+func (s *AuthService) RefreshClient(ctx context.Context, clientID, refreshToken string) (*AuthResponse, error) {
+	return s.refresh(ctx, clientID, refreshToken)
+}
+
+// refresh выполняет ротацию токена, выданного клиенту clientID (пустой - первая сторона)
+func (s *AuthService) refresh(ctx context.Context, clientID, refreshToken string) (*AuthResponse, error) {
+	stored, err := s.refreshRepo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, postgres.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// Токен другого клиента не принимается и не сжигается: предъявивший его не владелец
+	if stored.ClientID != clientID {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	// Refresh токены отзываются при приостановке и удалении; проверка на случай гонки
+	if user.IsSuspended() || user.IsDeleted() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	authResponse, next, err := s.newTokenPair(user, stored.FamilyID, stored.ClientID, stored.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Параллельный обмен того же токена тоже считается повторным использованием
+	if err := s.refreshRepo.RotateRefreshToken(ctx, stored.ID, next); err != nil {
+		if errors.Is(err, postgres.ErrRefreshTokenUsed) {
+			return nil, s.revokeReusedFamily(ctx, stored)
+		}
+		return nil, err
+	}
+
+	return authResponse, nil
+}
+
+// Logout отзывает текущий access токен и, если передан, семейство refresh токена
+func (s *AuthService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	if err := s.revoker.Revoke(ctx, claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshRepo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, postgres.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Чужой refresh токен не трогаем
+	if stored.UserID.String() != claims.UserID {
+		return nil
+	}
+
+	return s.refreshRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего. Аккаунт без пароля
+// (вход по ссылке или коду из письма) задает первый пароль без проверки.
+// Все ранее выданные токены пользователя отзываются; вызывающему выдается новая пара токенов,
+// чтобы текущая сессия продолжилась.
+func (s *AuthService) ChangePassword(ctx context.Context, claims *auth.Claims, req *models.ChangePasswordRequest) (*AuthResponse, error) {
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.HasPassword() {
+		if err := checkPasswordWithLockout(ctx, s.lockout, s, user, req.CurrentPassword, ErrWrongPassword); err != nil {
+			return nil, err
+		}
+		if req.NewPassword == req.CurrentPassword {
+			return nil, ErrPasswordUnchanged
+		}
+	}
+
+	passwordHash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		if errors.Is(err, ErrPasswordTooLong) {
+			return nil, err
+		}
+		return nil, errors.New("failed to hash password")
+	}
+
+	// Новое поколение делает недействительными все выданные access токены
+	generation, err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = passwordHash
+	user.TokenGeneration = generation
+	s.userCache.Delete(user.Email)
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔑 Password changed for user %s, other sessions revoked", user.ID)
+
+	return s.IssueTokens(ctx, user, claims.Scopes())
+}
+
+// revokeReusedFamily отзывает семейство токенов при обнаружении повторного использования
+func (s *AuthService) revokeReusedFamily(ctx context.Context, stored *models.RefreshToken) error {
+	log.Printf("⚠️ Refresh token reuse detected for user %s, revoking family %s", stored.UserID, stored.FamilyID)
+
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// newTokenPair генерирует access токен и новый refresh токен в указанном семействе
+func (s *AuthService) newTokenPair(user *models.User, familyID uuid.UUID, clientID, scope string) (*AuthResponse, *models.RefreshToken, error) {
+	now := time.Now()
+
+	// В refresh токене остаются запрошенные scopes, в access токене - действующие сейчас:
+	// после подтверждения адреса следующий обмен вернет scopes семейству
+	effectiveScope := s.effectiveScope(user, scope)
 
 	// Генерируем JWT токен
-	token, err := s.jwtService.GenerateToken(user.ID.String(), user.Email)
+	token, err := s.jwtService.GenerateToken(user.ID.String(), user.Email, user.TokenGeneration, strings.Fields(effectiveScope))
+	if err != nil {
+		return nil, nil, errors.New("failed to generate token")
+	}
+
+	rawRefreshToken, refreshTokenHash, err := auth.NewOpaqueToken()
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, nil, errors.New("failed to generate refresh token")
+	}
+
+	refreshToken := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ClientID:  clientID,
+		TokenHash: refreshTokenHash,
+		Scope:     scope,
+		ExpiresAt: now.Add(s.refreshExpiration),
+		CreatedAt: now,
 	}
 
 	return &AuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
+		User:                  user,
+		Token:                 token,
+		TokenExpiresAt:        now.Add(s.jwtService.Expiration()),
+		RefreshToken:          rawRefreshToken,
+		RefreshTokenExpiresAt: refreshToken.ExpiresAt,
+		Scope:                 effectiveScope,
+	}, refreshToken, nil
+}
+
+// effectiveScope ограничивает запрошенные scopes текущим состоянием пользователя.
+// Неподтвержденный адрес в режиме restrict оставляет токен без scopes: доступны только
+// маршруты, которым достаточно аутентификации.
+func (s *AuthService) effectiveScope(user *models.User, scope string) string {
+	if s.verificationPolicy == VerificationPolicyRestrict && !user.IsEmailVerified() {
+		return ""
+	}
+	return scope
 }
 
 // GetProfile получает профиль пользователя по ID
@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"auth-service/internal/auth"
 	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -40,18 +46,147 @@ func (m *MockUserRepository) GetUserByID(ctx context.Context, userID string) (*m
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) UserExists(ctx context.Context, email string) (bool, error) {
 	args := m.Called(ctx, email)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) error {
+	args := m.Called(ctx, userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) (int, error) {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpgradePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	args := m.Called(ctx, userID, oldHash, newHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) (int, error) {
+	args := m.Called(ctx, userID, email)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, purgeAfter time.Time, reason string) error {
+	args := m.Called(ctx, userID, purgeAfter, reason)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) (models.UserStatus, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(models.UserStatus), args.Error(1)
+}
+
+func (m *MockUserRepository) ListUsers(ctx context.Context, filter *models.UserListFilter) ([]models.User, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) ListStatusHistory(ctx context.Context, userID uuid.UUID) ([]models.UserStatusChange, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.UserStatusChange), args.Error(1)
+}
+
+func (m *MockUserRepository) RevokeTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) RequirePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, secret string, step int64) error {
+	args := m.Called(ctx, userID, secret, step)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ChangeStatus(ctx context.Context, userID uuid.UUID, status models.UserStatus, reason string) error {
+	args := m.Called(ctx, userID, status, reason)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, profile *models.Profile) (*models.User, error) {
+	args := m.Called(ctx, userID, profile)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) RecordFailedLogin(ctx context.Context, userID uuid.UUID, threshold int, lockFor, maxLockFor time.Duration) (*time.Time, error) {
+	args := m.Called(ctx, userID, threshold, lockFor, maxLockFor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockLoginLockout - мок учета неудачных входов
+type MockLoginLockout struct {
+	mock.Mock
+}
+
+func (m *MockLoginLockout) RecordFailure(ctx context.Context, user *models.User) (bool, error) {
+	args := m.Called(ctx, user)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLoginLockout) Reset(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
 // MockJWTService - мок JWT сервиса
 type MockJWTService struct {
 	mock.Mock
 }
 
-func (m *MockJWTService) GenerateToken(userID, email string) (string, error) {
-	args := m.Called(userID, email)
+func (m *MockJWTService) GenerateToken(userID, email string, generation int, scopes []string) (string, error) {
+	args := m.Called(userID, email, generation, scopes)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockJWTService) GenerateClientToken(clientID string, scopes []string) (string, error) {
+	args := m.Called(clientID, scopes)
 	return args.String(0), args.Error(1)
 }
 
@@ -63,11 +198,67 @@ func (m *MockJWTService) ValidateToken(tokenString string) (*auth.Claims, error)
 	return args.Get(0).(*auth.Claims), args.Error(1)
 }
 
+func (m *MockJWTService) Expiration() time.Duration {
+	return 15 * time.Minute
+}
+
+// MockRefreshTokenRepository - мок репозитория refresh токенов
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) error {
+	args := m.Called(ctx, oldID, next)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	args := m.Called(ctx, familyID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) ListUserRefreshTokens(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RefreshToken), args.Error(1)
+}
+
+// MockTokenRevoker - мок denylist access токенов
+type MockTokenRevoker struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevoker) Revoke(ctx context.Context, claims *auth.Claims) error {
+	args := m.Called(ctx, claims)
+	return args.Error(0)
+}
+
 func TestAuthService_Register_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockJWTService := new(MockJWTService)
-	authService := NewAuthService(mockUserRepo, mockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
 
 	req := &models.CreateUserRequest{
 		Email:    "test@example.com",
@@ -80,7 +271,8 @@ func TestAuthService_Register_Success(t *testing.T) {
 		ID:    [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
 		Email: req.Email,
 	}, nil)
-	mockJWTService.On("GenerateToken", mock.Anything, req.Email).Return("jwt-token", nil)
+	mockJWTService.On("GenerateToken", mock.Anything, req.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
 
 	// Act
 	result, err := authService.Register(context.Background(), req)
@@ -90,16 +282,20 @@ func TestAuthService_Register_Success(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, "test@example.com", result.User.Email)
 	assert.Equal(t, "jwt-token", result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.True(t, result.RefreshTokenExpiresAt.After(result.TokenExpiresAt))
 
 	mockUserRepo.AssertExpectations(t)
 	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
 }
 
 func TestAuthService_Register_UserExists(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockJWTService := new(MockJWTService)
-	authService := NewAuthService(mockUserRepo, mockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
 
 	req := &models.CreateUserRequest{
 		Email:    "existing@example.com",
@@ -124,7 +320,8 @@ func TestAuthService_Login_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockJWTService := new(MockJWTService)
-	authService := NewAuthService(mockUserRepo, mockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
 
 	req := &models.LoginRequest{
 		Email:    "test@example.com",
@@ -145,7 +342,8 @@ func TestAuthService_Login_Success(t *testing.T) {
 
 	// Настраиваем моки
 	mockUserRepo.On("GetUserByEmail", mock.Anything, req.Email).Return(user, nil)
-	mockJWTService.On("GenerateToken", mock.Anything, req.Email).Return("jwt-token", nil)
+	mockJWTService.On("GenerateToken", mock.Anything, req.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
 
 	// Act
 	result, err := authService.Login(context.Background(), req)
@@ -155,16 +353,20 @@ func TestAuthService_Login_Success(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, "test@example.com", result.User.Email)
 	assert.Equal(t, "jwt-token", result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.True(t, result.RefreshTokenExpiresAt.After(result.TokenExpiresAt))
 
 	mockUserRepo.AssertExpectations(t)
 	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
 }
 
 func TestAuthService_GetProfile_Success(t *testing.T) {
 	// Arrange
 	mockUserRepo := new(MockUserRepository)
 	mockJWTService := new(MockJWTService)
-	authService := NewAuthService(mockUserRepo, mockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
 
 	userID := "test-user-id"
 	user := &models.User{
@@ -185,3 +387,348 @@ func TestAuthService_GetProfile_Success(t *testing.T) {
 
 	mockUserRepo.AssertExpectations(t)
 }
+
+func TestAuthService_Refresh_RotatesToken(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := &models.User{
+		ID:    uuid.New(),
+		Email: "test@example.com",
+	}
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		Scope:     "profile",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	// Настраиваем моки
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("old-refresh-token")).Return(stored, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, []string{"profile"}).Return("new-jwt-token", nil)
+	mockRefreshRepo.On("RotateRefreshToken", mock.Anything, stored.ID, mock.MatchedBy(func(next *models.RefreshToken) bool {
+		// Новый токен остается в том же семействе и сохраняет scopes
+		return next.FamilyID == stored.FamilyID && next.UserID == user.ID && next.Scope == stored.Scope
+	})).Return(nil)
+
+	// Act
+	result, err := authService.Refresh(context.Background(), "old-refresh-token")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "new-jwt-token", result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.NotEqual(t, "old-refresh-token", result.RefreshToken)
+	assert.Equal(t, "profile", result.Scope)
+
+	mockUserRepo.AssertExpectations(t)
+	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	usedAt := time.Now().Add(-time.Minute)
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		FamilyID:  uuid.New(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	// Настраиваем моки
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("stolen-token")).Return(stored, nil)
+	mockRefreshRepo.On("RevokeRefreshTokenFamily", mock.Anything, stored.FamilyID).Return(nil)
+
+	// Act
+	result, err := authService.Refresh(context.Background(), "stolen-token")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Nil(t, result)
+
+	mockRefreshRepo.AssertExpectations(t)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Refresh_UnknownToken(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	// Настраиваем моки
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, mock.Anything).Return(nil, postgres.ErrRefreshTokenNotFound)
+
+	// Act
+	result, err := authService.Refresh(context.Background(), "unknown-token")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Nil(t, result)
+
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_RejectsOtherClientToken(t *testing.T) {
+	// Arrange
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(new(MockUserRepository), new(MockJWTService), mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	firstParty := &models.RefreshToken{ID: uuid.New(), UserID: uuid.New(), FamilyID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+	clientToken := &models.RefreshToken{ID: uuid.New(), UserID: uuid.New(), FamilyID: uuid.New(), ClientID: "spa", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("first-party")).Return(firstParty, nil)
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("client-token")).Return(clientToken, nil)
+
+	// Act & Assert: токен первой стороны не обменивается клиентом, токен клиента -
+	// через /auth/refresh и другим клиентом
+	_, err := authService.RefreshClient(context.Background(), "spa", "first-party")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = authService.Refresh(context.Background(), "client-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = authService.RefreshClient(context.Background(), "other", "client-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Чужой токен не сжигается и не отзывает семейство
+	mockRefreshRepo.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything)
+	mockRefreshRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Logout_RevokesAccessTokenAndRefreshFamily(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockRevoker := new(MockTokenRevoker)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, mockRevoker, new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	userID := uuid.New()
+	claims := &auth.Claims{UserID: userID.String()}
+	stored := &models.RefreshToken{
+		ID:       uuid.New(),
+		UserID:   userID,
+		FamilyID: uuid.New(),
+	}
+
+	// Настраиваем моки
+	mockRevoker.On("Revoke", mock.Anything, claims).Return(nil)
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("refresh-token")).Return(stored, nil)
+	mockRefreshRepo.On("RevokeRefreshTokenFamily", mock.Anything, stored.FamilyID).Return(nil)
+
+	// Act
+	err := authService.Logout(context.Background(), claims, "refresh-token")
+
+	// Assert
+	assert.NoError(t, err)
+
+	mockRevoker.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword_RevokesOtherSessions(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: uuid.New(), Email: "test@example.com", PasswordHash: string(currentHash), TokenGeneration: 3}
+	claims := &auth.Claims{UserID: user.ID.String(), Scope: "profile"}
+
+	// Настраиваем моки
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdatePassword", mock.Anything, user.ID, mock.AnythingOfType("string")).Return(4, nil)
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+	// Новый токен выдается в новом поколении - старые токены перестают приниматься
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 4, []string{"profile"}).Return("new-jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act
+	result, err := authService.ChangePassword(context.Background(), claims, &models.ChangePasswordRequest{
+		CurrentPassword: "old-password",
+		NewPassword:     "new-password",
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "new-jwt-token", result.Token)
+	mockUserRepo.AssertExpectations(t)
+	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword_PasswordlessSetsFirstPassword(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	claims := &auth.Claims{UserID: user.ID.String(), Scope: "profile"}
+
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdatePassword", mock.Anything, user.ID, mock.AnythingOfType("string")).Return(1, nil)
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 1, []string{"profile"}).Return("new-jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act: текущего пароля нет - проверять нечего
+	result, err := authService.ChangePassword(context.Background(), claims, &models.ChangePasswordRequest{
+		NewPassword: "new-password",
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "new-jwt-token", result.Token)
+	assert.True(t, user.HasPassword())
+	mockLockout.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword_Validation(t *testing.T) {
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: uuid.New(), Email: "test@example.com", PasswordHash: string(currentHash)}
+
+	tests := []struct {
+		name    string
+		req     *models.ChangePasswordRequest
+		wantErr error
+	}{
+		{"wrong current password", &models.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "new-password"}, ErrWrongPassword},
+		{"same password", &models.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: "old-password"}, ErrPasswordUnchanged},
+		{"over bcrypt limit", &models.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: strings.Repeat("a", 73)}, ErrPasswordTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUserRepo := new(MockUserRepository)
+			mockLockout := new(MockLoginLockout)
+			authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+			mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+			mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil)
+
+			// Act
+			result, err := authService.ChangePassword(context.Background(), &auth.Claims{UserID: user.ID.String()}, tt.req)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, result)
+			mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthService_Login_ByUsername(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	username := "tester"
+	user.Username = &username
+
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "Tester").Return(user, nil).Once()
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act: второй вход берет пользователя из кеша
+	for i := 0; i < 2; i++ {
+		result, err := authService.Login(context.Background(), &models.LoginRequest{Identifier: "Tester", Password: "password123"})
+		require.NoError(t, err)
+		assert.Equal(t, user.Email, result.User.Email)
+	}
+
+	// Assert
+	mockUserRepo.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Register_ReservedUsername(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	mockUserRepo.On("UserExists", mock.Anything, "test@example.com").Return(false, nil)
+
+	for username, expected := range map[string]error{
+		"Admin":    ErrUsernameReserved,
+		"ab":       ErrInvalidUsername,
+		"user@box": ErrInvalidUsername,
+		"-leading": ErrInvalidUsername,
+	} {
+		// Act
+		_, err := authService.Register(context.Background(), &models.CreateUserRequest{
+			Email:    "test@example.com",
+			Username: username,
+			Password: "password123",
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, expected, username)
+	}
+	mockUserRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_UpgradesImportedPasswordHash(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	// Хеш Django для пароля "correct horse"
+	importedHash := "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="
+	user := &models.User{
+		ID:           uuid.New(),
+		Email:        "imported@example.com",
+		PasswordHash: importedHash,
+	}
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("UpgradePasswordHash", mock.Anything, user.ID, importedHash, mock.MatchedBy(func(newHash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(newHash), []byte("correct horse")) == nil
+	})).Return(nil).Once()
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "correct horse"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "jwt-token", result.Token)
+	mockUserRepo.AssertExpectations(t)
+
+	// Неверный пароль к импортированному хешу - обычная ошибка входа
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil).Once()
+	_, err = authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "battery staple"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestFirstPartyScopesFor(t *testing.T) {
+	// Act & Assert: scope admin получают только администраторы
+	assert.Equal(t, firstPartyScopes, firstPartyScopesFor(&models.User{Role: models.RoleUser}))
+	assert.Equal(t, append(slices.Clone(firstPartyScopes), auth.ScopeAdmin), firstPartyScopesFor(&models.User{Role: models.RoleAdmin}))
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidExportToken - ссылка на выгрузку поддельная, использованная или истекшая
+var ErrInvalidExportToken = errors.New("invalid or expired data export link")
+
+// dataExportInlineWait - сколько запрос ждет фоновую сборку, чтобы отдать выгрузку сразу.
+// Выгрузка, не собранная за это время, отправляется ссылкой, даже если она небольшая.
+const dataExportInlineWait = 2 * time.Second
+
+// dataExportBuildTimeout - предельное время фоновой сборки выгрузки и отправки ссылки
+const dataExportBuildTimeout = 5 * time.Minute
+
+// dataExportNotRecorded - категории, которые сервис не хранит. Выгрузка называет их явно,
+// чтобы их отсутствие не выглядело пропуском.
+var dataExportNotRecorded = map[string]string{
+	"failed_logins": "individual failed sign-in attempts are not stored, only the current counters in login_security",
+	"consents":      "OAuth consent decisions are not stored; clients that were granted access are listed by client_id in login_history and sessions",
+}
+
+// DataExportFile - выгрузка данных пользователя, сериализованная в JSON
+type DataExportFile struct {
+	UserID string
+	Data   []byte
+}
+
+// dataExportResult - результат фоновой сборки для запроса, который ее ждет.
+// Пустой результат означает, что выгрузка будет отправлена ссылкой.
+type dataExportResult struct {
+	file *DataExportFile
+	err  error
+}
+
+// DataExportService собирает выгрузку персональных данных пользователя.
+// Выгрузка собирается в фоне; до inlineLimit байт она отдается сразу, большая
+// сохраняется до скачивания по одноразовой ссылке из письма.
+type DataExportService struct {
+	profiles    ProfileProvider
+	userRepo    UserRepository
+	refreshRepo RefreshTokenRepository
+	tokenRepo   UserTokenRepository
+	exportRepo  DataExportRepository
+	mailer      DataExportMailer
+	secret      []byte
+	baseURL     string
+	linkTTL     time.Duration
+	inlineLimit int
+	inlineWait  time.Duration
+
+	mu       sync.Mutex
+	building map[uuid.UUID]bool // пользователи, выгрузка которых сейчас собирается
+}
+
+func NewDataExportService(profiles ProfileProvider, userRepo UserRepository, refreshRepo RefreshTokenRepository, tokenRepo UserTokenRepository, exportRepo DataExportRepository, mailer DataExportMailer, secret, baseURL string, linkTTL time.Duration, inlineLimit int) *DataExportService {
+	return &DataExportService{
+		profiles:    profiles,
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		tokenRepo:   tokenRepo,
+		exportRepo:  exportRepo,
+		mailer:      mailer,
+		secret:      []byte(secret),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		linkTTL:     linkTTL,
+		inlineLimit: inlineLimit,
+		inlineWait:  dataExportInlineWait,
+		building:    make(map[uuid.UUID]bool),
+	}
+}
+
+// RequestExport запускает сборку выгрузки в фоне и ждет ее не дольше inlineWait.
+// Выгрузка до inlineLimit байт, собранная за это время, возвращается сразу. Иначе
+// возвращается queued = true: сборка продолжается в фоне, готовая выгрузка сохраняется,
+// и ссылка на нее отправляется на email. Пока выгрузка пользователя собирается,
+// повторный запрос новую сборку не запускает.
+func (s *DataExportService) RequestExport(ctx context.Context, userID string) (file *DataExportFile, queued bool, err error) {
+	user, err := s.profiles.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !s.startBuild(user.ID) {
+		return nil, true, nil
+	}
+
+	result := make(chan dataExportResult)
+	abandoned := make(chan struct{})
+	go s.build(user, result, abandoned)
+
+	timer := time.NewTimer(s.inlineWait)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		if r.err != nil {
+			return nil, false, r.err
+		}
+		return r.file, r.file == nil, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// Запрос больше не ждет: сборка доведет выгрузку до письма сама
+	close(abandoned)
+	return nil, true, nil
+}
+
+// build собирает выгрузку и передает результат запросу, если тот еще ждет.
+// Большая выгрузка и выгрузка, которую запрос не дождался, отправляются ссылкой.
+func (s *DataExportService) build(user *models.User, result chan<- dataExportResult, abandoned <-chan struct{}) {
+	defer s.finishBuild(user.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportBuildTimeout)
+	defer cancel()
+
+	file, err := s.export(ctx, user)
+	if err != nil {
+		select {
+		case result <- dataExportResult{err: err}:
+		case <-abandoned:
+			log.Printf("❌ Failed to build data export for user %s: %v", user.ID, err)
+		}
+		return
+	}
+
+	reply := dataExportResult{}
+	if len(file.Data) <= s.inlineLimit {
+		reply.file = file
+	}
+	select {
+	case result <- reply:
+		if reply.file != nil {
+			return
+		}
+	case <-abandoned:
+	}
+
+	if err := s.sendLink(ctx, user, file); err != nil {
+		log.Printf("❌ Failed to send data export link to user %s: %v", user.ID, err)
+	}
+}
+
+// sendLink сохраняет выгрузку, привязав ее к новой ссылке, и отправляет ссылку на email.
+// Скачать по ссылке можно именно этот снимок: при скачивании выгрузка не собирается заново.
+func (s *DataExportService) sendLink(ctx context.Context, user *models.User, file *DataExportFile) error {
+	token, tokenID, err := createUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenDataExport, user.ID, user.Email, "", s.linkTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.exportRepo.SaveDataExport(ctx, tokenID, user.ID, file.Data, time.Now().Add(s.linkTTL)); err != nil {
+		return err
+	}
+
+	link := s.baseURL + "/auth/account/export/download?token=" + url.QueryEscape(token)
+	if err := s.mailer.SendDataExportEmail(user.Email, link); err != nil {
+		// Ссылка не дошла до пользователя - снимок вместе с ней больше не нужен
+		if cleanupErr := s.tokenRepo.DeleteUserTokens(ctx, user.ID, models.UserTokenDataExport); cleanupErr != nil {
+			log.Printf("❌ Failed to delete undelivered data export for user %s: %v", user.ID, cleanupErr)
+		}
+		return err
+	}
+
+	log.Printf("📦 Data export link sent to user %s (%d bytes)", user.ID, len(file.Data))
+	return nil
+}
+
+// startBuild отмечает, что выгрузка пользователя собирается; false - она уже собирается
+func (s *DataExportService) startBuild(userID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.building[userID] {
+		return false
+	}
+	s.building[userID] = true
+	return true
+}
+
+// finishBuild снимает отметку о сборке выгрузки пользователя
+func (s *DataExportService) finishBuild(userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.building, userID)
+}
+
+// DownloadExport возвращает выгрузку, сохраненную для одноразовой ссылки из письма.
+// Ссылка действует, только пока адрес пользователя совпадает с адресом письма.
+func (s *DataExportService) DownloadExport(ctx context.Context, token string) (*DataExportFile, error) {
+	stored, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenDataExport, token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return nil, ErrInvalidExportToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidExportToken
+		}
+		return nil, err
+	}
+	if user.Email != stored.Email || user.IsDeleted() {
+		return nil, ErrInvalidExportToken
+	}
+
+	data, err := s.exportRepo.TakeDataExport(ctx, stored.ID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrDataExportNotFound) {
+			return nil, ErrInvalidExportToken
+		}
+		return nil, err
+	}
+
+	return &DataExportFile{
+		UserID: user.ID.String(),
+		Data:   data,
+	}, nil
+}
+
+// Prune удаляет выгрузки, ссылки на которые истекли
+func (s *DataExportService) Prune(ctx context.Context) error {
+	deleted, err := s.exportRepo.DeleteExpiredDataExports(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("🧹 Pruned %d expired data exports", deleted)
+	}
+	return nil
+}
+
+// StartPruning периодически удаляет нескачанные выгрузки, пока не отменен ctx
+func (s *DataExportService) StartPruning(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Prune(ctx); err != nil {
+				log.Printf("❌ Failed to prune data exports: %v", err)
+			}
+		}
+	}
+}
+
+// export собирает все данные пользователя (профиль, сессии и входы, журнал статуса,
+// ссылки из писем) и сериализует их
+func (s *DataExportService) export(ctx context.Context, user *models.User) (*DataExportFile, error) {
+	sessions, err := s.refreshRepo.ListUserRefreshTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog, err := s.userRepo.ListStatusHistory(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailLinks, err := s.tokenRepo.ListUserTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(&models.DataExport{
+		ExportedAt:   time.Now().UTC(),
+		Profile:      user,
+		Sessions:     sessions,
+		LoginHistory: loginHistory(sessions),
+		LoginSecurity: models.DataExportLoginSecurity{
+			FailedLoginAttempts: user.FailedLoginAttempts,
+			LockoutCount:        user.LockoutCount,
+			LockedUntil:         user.LockedUntil,
+		},
+		AuditLog:    auditLog,
+		EmailLinks:  emailLinks,
+		NotRecorded: dataExportNotRecorded,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataExportFile{
+		UserID: user.ID.String(),
+		Data:   data,
+	}, nil
+}
+
+// loginHistory восстанавливает успешные входы по сессиям: каждый вход начинает
+// семейство refresh токенов, а временем входа считается выдача первого токена семейства
+func loginHistory(sessions []models.RefreshToken) []models.DataExportLogin {
+	byFamily := make(map[uuid.UUID]int)
+	logins := []models.DataExportLogin{}
+	for _, session := range sessions {
+		i, ok := byFamily[session.FamilyID]
+		if !ok {
+			byFamily[session.FamilyID] = len(logins)
+			logins = append(logins, models.DataExportLogin{
+				SessionID:  session.FamilyID,
+				ClientID:   session.ClientID,
+				LoggedInAt: session.CreatedAt,
+			})
+			continue
+		}
+		if session.CreatedAt.Before(logins[i].LoggedInAt) {
+			logins[i].LoggedInAt = session.CreatedAt
+		}
+	}
+
+	sort.Slice(logins, func(i, j int) bool {
+		return logins[i].LoggedInAt.After(logins[j].LoggedInAt)
+	})
+	return logins
+}
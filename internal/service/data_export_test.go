@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockProfileProvider - мок получения профиля
+type MockProfileProvider struct {
+	mock.Mock
+}
+
+func (m *MockProfileProvider) GetProfile(ctx context.Context, userID string) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// MockDataExportRepository - мок хранилища собранных выгрузок
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) SaveDataExport(ctx context.Context, tokenID, userID uuid.UUID, data []byte, expiresAt time.Time) error {
+	args := m.Called(ctx, tokenID, userID, data, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) TakeDataExport(ctx context.Context, tokenID uuid.UUID) ([]byte, error) {
+	args := m.Called(ctx, tokenID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockDataExportRepository) DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// waitForSignal ждет окончания фоновой работы сервиса
+func waitForSignal(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("background data export did not finish")
+	}
+}
+
+func TestDataExportService_RequestExport_Inline(t *testing.T) {
+	// Arrange
+	mockProfiles := new(MockProfileProvider)
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockMailer := new(MockMailer)
+	exportService := NewDataExportService(mockProfiles, mockUserRepo, mockRefreshRepo, mockTokenRepo, new(MockDataExportRepository), mockMailer, testTokenSecret, "https://auth.example.com", time.Hour, 1<<20)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	user := &models.User{ID: uuid.New(), Email: "test@example.com", FailedLoginAttempts: 2, LockedUntil: &lockedUntil}
+	sessions := []models.RefreshToken{{ID: uuid.New(), UserID: user.ID, FamilyID: uuid.New()}}
+	history := []models.UserStatusChange{{ID: uuid.New(), UserID: user.ID, FromStatus: models.UserStatusPending, ToStatus: models.UserStatusActive, Reason: models.StatusReasonEmailVerified}}
+	mockProfiles.On("GetProfile", mock.Anything, user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("ListUserRefreshTokens", mock.Anything, user.ID).Return(sessions, nil)
+	mockUserRepo.On("ListStatusHistory", mock.Anything, user.ID).Return(history, nil)
+	mockTokenRepo.On("ListUserTokens", mock.Anything, user.ID).Return([]models.UserToken{}, nil)
+
+	// Act
+	file, queued, err := exportService.RequestExport(context.Background(), user.ID.String())
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, user.ID.String(), file.UserID)
+
+	var export models.DataExport
+	require.NoError(t, json.Unmarshal(file.Data, &export))
+	assert.Equal(t, user.Email, export.Profile.Email)
+	assert.Len(t, export.Sessions, 1)
+	require.Len(t, export.LoginHistory, 1)
+	assert.Equal(t, sessions[0].FamilyID, export.LoginHistory[0].SessionID)
+	assert.Equal(t, 2, export.LoginSecurity.FailedLoginAttempts)
+	assert.NotNil(t, export.LoginSecurity.LockedUntil)
+	require.Len(t, export.AuditLog, 1)
+	assert.Equal(t, models.StatusReasonEmailVerified, export.AuditLog[0].Reason)
+	assert.Contains(t, export.NotRecorded, "consents")
+	mockMailer.AssertNotCalled(t, "SendDataExportEmail", mock.Anything, mock.Anything)
+}
+
+func TestDataExportService_RequestExport_LargeAccountEmailsStoredSnapshot(t *testing.T) {
+	// Arrange
+	mockProfiles := new(MockProfileProvider)
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockExportRepo := new(MockDataExportRepository)
+	mockMailer := new(MockMailer)
+	exportService := NewDataExportService(mockProfiles, mockUserRepo, mockRefreshRepo, mockTokenRepo, mockExportRepo, mockMailer, testTokenSecret, "https://auth.example.com", time.Hour, 1)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	var tokenID uuid.UUID
+	sent := make(chan struct{})
+	mockProfiles.On("GetProfile", mock.Anything, user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("ListUserRefreshTokens", mock.Anything, user.ID).Return([]models.RefreshToken{{ID: uuid.New()}, {ID: uuid.New()}}, nil)
+	mockUserRepo.On("ListStatusHistory", mock.Anything, user.ID).Return([]models.UserStatusChange{}, nil)
+	mockTokenRepo.On("ListUserTokens", mock.Anything, user.ID).Return([]models.UserToken{}, nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.Purpose == models.UserTokenDataExport && token.Email == user.Email
+	})).Run(func(args mock.Arguments) {
+		tokenID = args.Get(1).(*models.UserToken).ID
+	}).Return(nil)
+	mockExportRepo.On("SaveDataExport", mock.Anything, mock.AnythingOfType("uuid.UUID"), user.ID, mock.Anything, mock.Anything).Return(nil)
+	mockMailer.On("SendDataExportEmail", user.Email, mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, "https://auth.example.com/auth/account/export/download?token=")
+	})).Run(func(mock.Arguments) { close(sent) }).Return(nil)
+
+	// Act
+	file, queued, err := exportService.RequestExport(context.Background(), user.ID.String())
+	waitForSignal(t, sent)
+
+	// Assert: выгрузка не отдается в ответе; собранный снимок сохранен под токеном ссылки
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.Nil(t, file)
+	mockExportRepo.AssertCalled(t, "SaveDataExport", mock.Anything, tokenID, user.ID, mock.Anything, mock.Anything)
+	mockRefreshRepo.AssertNumberOfCalls(t, "ListUserRefreshTokens", 1)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestDataExportService_RequestExport_SlowBuildIsEmailed(t *testing.T) {
+	// Arrange
+	mockProfiles := new(MockProfileProvider)
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockExportRepo := new(MockDataExportRepository)
+	mockMailer := new(MockMailer)
+	exportService := NewDataExportService(mockProfiles, mockUserRepo, mockRefreshRepo, mockTokenRepo, mockExportRepo, mockMailer, testTokenSecret, "https://auth.example.com", time.Hour, 1<<20)
+	exportService.inlineWait = time.Millisecond
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	release := make(chan struct{})
+	sent := make(chan struct{})
+	mockProfiles.On("GetProfile", mock.Anything, user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("ListUserRefreshTokens", mock.Anything, user.ID).Run(func(mock.Arguments) { <-release }).Return([]models.RefreshToken{}, nil)
+	mockUserRepo.On("ListStatusHistory", mock.Anything, user.ID).Return([]models.UserStatusChange{}, nil)
+	mockTokenRepo.On("ListUserTokens", mock.Anything, user.ID).Return([]models.UserToken{}, nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.Anything).Return(nil)
+	mockExportRepo.On("SaveDataExport", mock.Anything, mock.Anything, user.ID, mock.Anything, mock.Anything).Return(nil)
+	mockMailer.On("SendDataExportEmail", user.Email, mock.Anything).Run(func(mock.Arguments) { close(sent) }).Return(nil)
+
+	// Act
+	file, queued, err := exportService.RequestExport(context.Background(), user.ID.String())
+
+	// Повторный запрос, пока выгрузка собирается, новую сборку не запускает
+	_, queuedAgain, errAgain := exportService.RequestExport(context.Background(), user.ID.String())
+	close(release)
+	waitForSignal(t, sent)
+
+	// Assert: запрос не дождался сборки - небольшая выгрузка все равно уходит ссылкой
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.Nil(t, file)
+	require.NoError(t, errAgain)
+	assert.True(t, queuedAgain)
+	mockRefreshRepo.AssertNumberOfCalls(t, "ListUserRefreshTokens", 1)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestDataExportService_RequestExport_UndeliveredLinkIsDeleted(t *testing.T) {
+	// Arrange
+	mockProfiles := new(MockProfileProvider)
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockExportRepo := new(MockDataExportRepository)
+	mockMailer := new(MockMailer)
+	exportService := NewDataExportService(mockProfiles, mockUserRepo, mockRefreshRepo, mockTokenRepo, mockExportRepo, mockMailer, testTokenSecret, "https://auth.example.com", time.Hour, 1)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	deleted := make(chan struct{})
+	mockProfiles.On("GetProfile", mock.Anything, user.ID.String()).Return(user, nil)
+	mockRefreshRepo.On("ListUserRefreshTokens", mock.Anything, user.ID).Return([]models.RefreshToken{}, nil)
+	mockUserRepo.On("ListStatusHistory", mock.Anything, user.ID).Return([]models.UserStatusChange{}, nil)
+	mockTokenRepo.On("ListUserTokens", mock.Anything, user.ID).Return([]models.UserToken{}, nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.Anything).Return(nil)
+	mockExportRepo.On("SaveDataExport", mock.Anything, mock.Anything, user.ID, mock.Anything, mock.Anything).Return(nil)
+	mockMailer.On("SendDataExportEmail", user.Email, mock.Anything).Return(assert.AnError)
+	mockTokenRepo.On("DeleteUserTokens", mock.Anything, user.ID, models.UserTokenDataExport).Run(func(mock.Arguments) { close(deleted) }).Return(nil)
+
+	// Act
+	_, queued, err := exportService.RequestExport(context.Background(), user.ID.String())
+	waitForSignal(t, deleted)
+
+	// Assert: ссылка не отправлена - токен и привязанный к нему снимок удалены
+	require.NoError(t, err)
+	assert.True(t, queued)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestDataExportService_DownloadExport_ReturnsStoredSnapshot(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockExportRepo := new(MockDataExportRepository)
+	exportService := NewDataExportService(new(MockProfileProvider), mockUserRepo, mockRefreshRepo, mockTokenRepo, mockExportRepo, new(MockMailer), testTokenSecret, "https://auth.example.com", time.Hour, 1)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	tokenID := uuid.New()
+	snapshot := []byte(`{"profile":{}}`)
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenDataExport)
+	require.NoError(t, err)
+
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenDataExport, tokenHash).Return(&models.UserToken{
+		ID:        tokenID,
+		UserID:    user.ID,
+		Purpose:   models.UserTokenDataExport,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockExportRepo.On("TakeDataExport", mock.Anything, tokenID).Return(snapshot, nil)
+
+	// Act
+	file, err := exportService.DownloadExport(context.Background(), token)
+
+	// Assert: отдается сохраненный снимок, выгрузка заново не собирается
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, file.Data)
+	assert.Equal(t, user.ID.String(), file.UserID)
+	mockRefreshRepo.AssertNotCalled(t, "ListUserRefreshTokens", mock.Anything, mock.Anything)
+}
+
+func TestDataExportService_DownloadExport_EmailChanged(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockExportRepo := new(MockDataExportRepository)
+	exportService := NewDataExportService(new(MockProfileProvider), mockUserRepo, new(MockRefreshTokenRepository), mockTokenRepo, mockExportRepo, new(MockMailer), testTokenSecret, "https://auth.example.com", time.Hour, 1)
+
+	user := &models.User{ID: uuid.New(), Email: "new@example.com"}
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenDataExport)
+	require.NoError(t, err)
+
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenDataExport, tokenHash).Return(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenDataExport,
+		Email:     "old@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+	// Act
+	file, err := exportService.DownloadExport(context.Background(), token)
+
+	// Assert: ссылка, отправленная на прежний адрес, больше не действует
+	assert.ErrorIs(t, err, ErrInvalidExportToken)
+	assert.Nil(t, file)
+	mockExportRepo.AssertNotCalled(t, "TakeDataExport", mock.Anything, mock.Anything)
+}
+
+func TestLoginHistory_OneEntryPerSessionFamily(t *testing.T) {
+	// Arrange
+	loggedInAt := time.Now().Add(-2 * time.Hour)
+	firstFamily := uuid.New()
+	secondFamily := uuid.New()
+	sessions := []models.RefreshToken{
+		{FamilyID: secondFamily, ClientID: "client", CreatedAt: loggedInAt.Add(time.Hour)},
+		{FamilyID: firstFamily, CreatedAt: loggedInAt.Add(30 * time.Minute)},
+		{FamilyID: firstFamily, CreatedAt: loggedInAt},
+	}
+
+	// Act
+	logins := loginHistory(sessions)
+
+	// Assert: ротация не считается новым входом, входы идут от новых к старым
+	require.Len(t, logins, 2)
+	assert.Equal(t, secondFamily, logins[0].SessionID)
+	assert.Equal(t, "client", logins[0].ClientID)
+	assert.Equal(t, firstFamily, logins[1].SessionID)
+	assert.True(t, logins[1].LoggedInAt.Equal(loggedInAt))
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidEmail - адрес не удалось привести к канонической форме
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// NormalizeEmail приводит адрес к канонической форме, в которой он хранится и
+// сравнивается: без пробелов по краям, в Unicode NFC, в нижнем регистре,
+// с доменом в punycode (IDN). Так Bob@X.com и bob@x.com - один аккаунт.
+func NormalizeEmail(email string) (string, error) {
+	email = norm.NFC.String(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", ErrInvalidEmail
+	}
+
+	local := strings.ToLower(email[:at])
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(email[at+1:]), "."))
+	if err != nil || domain == "" {
+		return "", ErrInvalidEmail
+	}
+
+	return local + "@" + domain, nil
+}
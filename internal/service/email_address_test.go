@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{email: "bob@x.com", expected: "bob@x.com"},
+		{email: "  Bob@X.COM ", expected: "bob@x.com"},
+		{email: "bob@x.com.", expected: "bob@x.com"},
+		{email: "Иван@Пример.РФ", expected: "иван@xn--e1afmkfd.xn--p1ai"},
+		// é как e + комбинируемый акут приводится к одному символу (NFC)
+		{email: "re\u0301sume@example.com", expected: "r\u00e9sume@example.com"},
+		{email: "user+tag@münchen.de", expected: "user+tag@xn--mnchen-3ya.de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			normalized, err := NormalizeEmail(tt.email)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestNormalizeEmail_Invalid(t *testing.T) {
+	for _, email := range []string{"", "bob", "@x.com", "bob@", "bob@exa mple.com"} {
+		_, err := NormalizeEmail(email)
+		assert.ErrorIs(t, err, ErrInvalidEmail, email)
+	}
+}
+
+func TestAuthService_Login_NormalizesEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(user, nil).Once()
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act: разные написания одного адреса попадают в один аккаунт и одну запись кеша
+	for _, email := range []string{" Test@Example.com", "TEST@EXAMPLE.COM"} {
+		_, err := authService.Login(context.Background(), &models.LoginRequest{Email: email, Password: "password123"})
+		assert.NoError(t, err)
+	}
+
+	// Assert
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_LegacyIDNEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	// Адрес с доменом IDN, который еще не переписан в punycode
+	user := newUnverifiedUser(t)
+	user.Email = "bob@пример.рф"
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "bob@xn--e1afmkfd.xn--p1ai").Return(user, nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act
+	_, err := authService.Login(context.Background(), &models.LoginRequest{Email: "Bob@Пример.РФ", Password: "password123"})
+
+	// Assert: поиск идет по канонической форме, кеш хранит пользователя под хранимым адресом
+	require.NoError(t, err)
+	assert.NotNil(t, authService.userCache.Get(user.Email))
+	mockUserRepo.AssertExpectations(t)
+}
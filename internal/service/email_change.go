@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// Ошибки смены email
+var (
+	ErrEmailUnchanged          = errors.New("new email must differ from the current one")
+	ErrEmailTaken              = errors.New("email is already in use")
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+)
+
+// EmailChangeService меняет адрес пользователя с двойным подтверждением:
+// ссылка для подтверждения уходит на новый адрес, уведомление со ссылкой
+// для отмены - на прежний
+type EmailChangeService struct {
+	userRepo    UserRepository
+	refreshRepo RefreshTokenRepository
+	tokenRepo   UserTokenRepository
+	mailer      EmailChangeMailer
+	lockout     LoginLockout
+	codes       EmailCodeVerifier
+	users       UserCacheInvalidator
+	secret      []byte
+	baseURL     string
+	confirmTTL  time.Duration
+	undoTTL     time.Duration
+}
+
+func NewEmailChangeService(userRepo UserRepository, refreshRepo RefreshTokenRepository, tokenRepo UserTokenRepository, mailer EmailChangeMailer, lockout LoginLockout, codes EmailCodeVerifier, users UserCacheInvalidator, secret, baseURL string, confirmTTL, undoTTL time.Duration) *EmailChangeService {
+	return &EmailChangeService{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		tokenRepo:   tokenRepo,
+		mailer:      mailer,
+		lockout:     lockout,
+		codes:       codes,
+		users:       users,
+		secret:      []byte(secret),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		confirmTTL:  confirmTTL,
+		undoTTL:     undoTTL,
+	}
+}
+
+// RequestEmailChange проверяет пароль (в аккаунте без пароля - код из письма)
+// и отправляет письма на новый и прежний адреса.
+// Адрес меняется только после перехода по ссылке из письма на новый адрес.
+func (s *EmailChangeService) RequestEmailChange(ctx context.Context, userID string, req *models.ChangeEmailRequest) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := reauthenticate(ctx, s.lockout, s.users, s.codes, user, req.Password, req.Code, ErrWrongPassword); err != nil {
+		return err
+	}
+
+	newEmail, err := NormalizeEmail(req.NewEmail)
+	if err != nil {
+		return err
+	}
+	req.NewEmail = newEmail
+	if req.NewEmail == user.Email {
+		return ErrEmailUnchanged
+	}
+
+	exists, err := s.userRepo.UserExists(ctx, req.NewEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailTaken
+	}
+
+	confirmToken, err := issueUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenChangeEmail, user.ID, req.NewEmail, s.confirmTTL)
+	if err != nil {
+		return err
+	}
+	undoToken, err := issueUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenUndoEmailChange, user.ID, user.Email, s.undoTTL)
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendEmailChangeConfirmationAsync(req.NewEmail, s.baseURL+"/auth/email/confirm?token="+url.QueryEscape(confirmToken))
+	s.mailer.SendEmailChangeNoticeAsync(user.Email, req.NewEmail, s.baseURL+"/auth/email/undo?token="+url.QueryEscape(undoToken))
+	log.Printf("📨 Email change requested for user %s", user.ID)
+
+	return nil
+}
+
+// ConfirmEmailChange меняет адрес по ссылке из письма на новый адрес.
+// Токены со старым email перестают приниматься; новые токены получат новый адрес.
+func (s *EmailChangeService) ConfirmEmailChange(ctx context.Context, token string) error {
+	stored, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenChangeEmail, token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return ErrInvalidEmailChangeToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return err
+	}
+
+	if err := s.updateEmail(ctx, user, stored.Email); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Email changed for user %s", user.ID)
+	return nil
+}
+
+// UndoEmailChange отменяет смену адреса по ссылке из письма на прежний адрес.
+// Если смена еще не подтверждена, ссылка подтверждения перестает действовать;
+// если уже подтверждена - возвращается прежний адрес и завершаются все сессии.
+func (s *EmailChangeService) UndoEmailChange(ctx context.Context, token string) error {
+	stored, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenUndoEmailChange, token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return ErrInvalidEmailChangeToken
+		}
+		return err
+	}
+
+	if err := s.tokenRepo.DeleteUserTokens(ctx, stored.UserID, models.UserTokenChangeEmail); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return err
+	}
+	if user.Email == stored.Email {
+		log.Printf("↩️ Pending email change cancelled for user %s", user.ID)
+		return nil
+	}
+
+	// Адрес мог сменить злоумышленник - завершаем все его сессии
+	if err := s.updateEmail(ctx, user, stored.Email); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	log.Printf("↩️ Email change reverted for user %s, sessions revoked", user.ID)
+	return nil
+}
+
+// updateEmail сохраняет новый адрес и сбрасывает кеш для прежнего и нового адресов
+func (s *EmailChangeService) updateEmail(ctx context.Context, user *models.User, email string) error {
+	if _, err := s.userRepo.UpdateEmail(ctx, user.ID, email); err != nil {
+		if errors.Is(err, postgres.ErrEmailTaken) {
+			return ErrEmailTaken
+		}
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return err
+	}
+
+	s.users.InvalidateUser(user.Email)
+	s.users.InvalidateUser(email)
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEmailChangeService(userRepo *MockUserRepository, refreshRepo *MockRefreshTokenRepository, tokenRepo *MockUserTokenRepository, mailer *MockMailer, users *MockUserCacheInvalidator) *EmailChangeService {
+	return NewEmailChangeService(userRepo, refreshRepo, tokenRepo, mailer, new(MockLoginLockout), new(MockEmailCodeVerifier), users, testTokenSecret, "https://auth.example.com", time.Hour, 24*time.Hour)
+}
+
+func TestEmailChangeService_RequestEmailChange(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockMailer := new(MockMailer)
+	emailChangeService := newTestEmailChangeService(mockUserRepo, new(MockRefreshTokenRepository), mockTokenRepo, mockMailer, new(MockUserCacheInvalidator))
+
+	user := newUnverifiedUser(t)
+
+	// Настраиваем моки: подтверждение - на новый адрес, отмена - на прежний
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UserExists", mock.Anything, "new@example.com").Return(false, nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.Purpose == models.UserTokenChangeEmail && token.Email == "new@example.com"
+	})).Return(nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.Purpose == models.UserTokenUndoEmailChange && token.Email == user.Email
+	})).Return(nil)
+	mockMailer.On("SendEmailChangeConfirmationAsync", "new@example.com", mock.AnythingOfType("string")).Return()
+	mockMailer.On("SendEmailChangeNoticeAsync", user.Email, "new@example.com", mock.AnythingOfType("string")).Return()
+
+	// Act
+	err := emailChangeService.RequestEmailChange(context.Background(), user.ID.String(), &models.ChangeEmailRequest{
+		NewEmail: "new@example.com",
+		Password: "password123",
+	})
+
+	// Assert: адрес не меняется до подтверждения
+	assert.NoError(t, err)
+	mockUserRepo.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything)
+	mockTokenRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestEmailChangeService_ConfirmEmailChange(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockCache := new(MockUserCacheInvalidator)
+	emailChangeService := newTestEmailChangeService(mockUserRepo, new(MockRefreshTokenRepository), mockTokenRepo, new(MockMailer), mockCache)
+
+	user := &models.User{ID: uuid.New(), Email: "old@example.com"}
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenChangeEmail)
+	require.NoError(t, err)
+
+	// Настраиваем моки
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenChangeEmail, tokenHash).Return(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenChangeEmail,
+		Email:     "new@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdateEmail", mock.Anything, user.ID, "new@example.com").Return(1, nil)
+	mockCache.On("InvalidateUser", "old@example.com").Return()
+	mockCache.On("InvalidateUser", "new@example.com").Return()
+
+	// Act
+	err = emailChangeService.ConfirmEmailChange(context.Background(), token)
+
+	// Assert: кеш сброшен для обоих адресов
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestEmailChangeService_UndoRevertsConfirmedChange(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockCache := new(MockUserCacheInvalidator)
+	emailChangeService := newTestEmailChangeService(mockUserRepo, mockRefreshRepo, mockTokenRepo, new(MockMailer), mockCache)
+
+	// Смена уже подтверждена: у пользователя новый адрес
+	user := &models.User{ID: uuid.New(), Email: "new@example.com"}
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenUndoEmailChange)
+	require.NoError(t, err)
+
+	// Настраиваем моки
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenUndoEmailChange, tokenHash).Return(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenUndoEmailChange,
+		Email:     "old@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockTokenRepo.On("DeleteUserTokens", mock.Anything, user.ID, models.UserTokenChangeEmail).Return(nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdateEmail", mock.Anything, user.ID, "old@example.com").Return(2, nil)
+	mockCache.On("InvalidateUser", mock.Anything).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	err = emailChangeService.UndoEmailChange(context.Background(), token)
+
+	// Assert: прежний адрес возвращен, сессии завершены
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidOTP - код неверный, истекший, уже использованный или попытки проверки исчерпаны
+var ErrInvalidOTP = errors.New("invalid or expired code")
+
+// EmailOTPService - одноразовые шестизначные коды из писем.
+// Коды разных назначений (purpose) независимы, поэтому кроме входа по коду сервис
+// годится для второго фактора и подтверждения чувствительных действий: вызывающий
+// отправляет код через SendCode и проверяет его через VerifyCode со своим назначением.
+type EmailOTPService struct {
+	userRepo    UserRepository
+	otpRepo     EmailOTPRepository
+	requests    EmailRequestRepository
+	mailer      OTPMailer
+	tokens      LoginCompleter
+	users       UserCacheInvalidator
+	secret      []byte
+	codeTTL     time.Duration
+	maxAttempts int
+	rateLimit   EmailRateLimit
+}
+
+func NewEmailOTPService(userRepo UserRepository, otpRepo EmailOTPRepository, requests EmailRequestRepository, mailer OTPMailer, tokens LoginCompleter, users UserCacheInvalidator, secret string, codeTTL time.Duration, maxAttempts int, rateLimit EmailRateLimit) *EmailOTPService {
+	return &EmailOTPService{
+		userRepo:    userRepo,
+		otpRepo:     otpRepo,
+		requests:    requests,
+		mailer:      mailer,
+		tokens:      tokens,
+		users:       users,
+		secret:      []byte(secret),
+		codeTTL:     codeTTL,
+		maxAttempts: maxAttempts,
+		rateLimit:   rateLimit,
+	}
+}
+
+// SendCode отправляет пользователю код указанного назначения.
+// Прежний неиспользованный код того же назначения перестает действовать.
+func (s *EmailOTPService) SendCode(ctx context.Context, user *models.User, purpose string) error {
+	if err := allowEmailRequest(ctx, s.requests, s.rateLimit, user.Email, otpRequestPurpose(purpose)); err != nil {
+		return err
+	}
+	return s.issueCode(ctx, user, purpose)
+}
+
+// VerifyCode проверяет код указанного назначения и при успехе гасит его.
+// Каждая проверка расходует попытку; после maxAttempts неудачных код не принимается,
+// даже верный. Для любого неподходящего кода возвращает ErrInvalidOTP.
+func (s *EmailOTPService) VerifyCode(ctx context.Context, user *models.User, purpose, code string) error {
+	otp, err := s.otpRepo.AttemptEmailOTP(ctx, user.ID, purpose, s.maxAttempts)
+	if err != nil {
+		if errors.Is(err, postgres.ErrEmailOTPNotFound) {
+			return ErrInvalidOTP
+		}
+		return err
+	}
+
+	// Письмо ушло на прежний адрес - после смены email код не действует
+	if time.Now().After(otp.ExpiresAt) || otp.Email != user.Email {
+		return ErrInvalidOTP
+	}
+	if !auth.CheckOTPCode(s.secret, purpose, user.ID.String(), code, otp.CodeHash) {
+		return ErrInvalidOTP
+	}
+
+	// Код гасится удалением: из параллельных проверок верного кода проходит одна
+	if err := s.otpRepo.DeleteEmailOTP(ctx, otp.ID); err != nil {
+		if errors.Is(err, postgres.ErrEmailOTPNotFound) {
+			return ErrInvalidOTP
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RequestLoginCode отправляет код для входа. Для неизвестных, удаленных и
+// приостановленных аккаунтов письмо не отправляется, но ответ тот же,
+// чтобы по нему нельзя было узнать, зарегистрирован ли адрес.
+func (s *EmailOTPService) RequestLoginCode(ctx context.Context, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	if err := allowEmailRequest(ctx, s.requests, s.rateLimit, email, otpRequestPurpose(models.EmailOTPLogin)); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.IsDeleted() || user.IsSuspended() {
+		return nil
+	}
+
+	return s.issueCode(ctx, user, models.EmailOTPLogin)
+}
+
+// LoginWithCode обменивает код из письма на пару токенов, как при входе по паролю.
+// Верный код подтверждает адрес.
+func (s *EmailOTPService) LoginWithCode(ctx context.Context, email, code string) (*AuthResponse, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidOTP
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidOTP
+		}
+		return nil, err
+	}
+
+	if err := s.VerifyCode(ctx, user, models.EmailOTPLogin, code); err != nil {
+		return nil, err
+	}
+
+	if user, err = completeEmailLogin(ctx, s.userRepo, s.users, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔢 Email code login for user %s", user.ID)
+	return s.tokens.CompleteLogin(ctx, user)
+}
+
+// RequestReauthCode отправляет пользователю без пароля код для подтверждения
+// удаления аккаунта и смены email. Аккаунт с паролем подтверждает их паролем.
+func (s *EmailOTPService) RequestReauthCode(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.HasPassword() {
+		return ErrPasswordReauthRequired
+	}
+
+	return s.SendCode(ctx, user, models.EmailOTPReauth)
+}
+
+// issueCode генерирует код, сохраняет его хеш и отправляет код письмом
+func (s *EmailOTPService) issueCode(ctx context.Context, user *models.User, purpose string) error {
+	code, err := auth.NewOTPCode()
+	if err != nil {
+		return errors.New("failed to generate code")
+	}
+
+	now := time.Now()
+	err = s.otpRepo.CreateEmailOTP(ctx, &models.EmailOTP{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		CodeHash:  auth.HashOTPCode(s.secret, purpose, user.ID.String(), code),
+		Email:     user.Email,
+		ExpiresAt: now.Add(s.codeTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendOTPEmailAsync(user.Email, code, s.codeTTL)
+	log.Printf("📨 OTP email (%s) queued for: %s", purpose, user.Email)
+
+	return nil
+}
+
+// otpRequestPurpose - назначение для учета частоты писем с кодами,
+// чтобы не смешивать их с письмами со ссылками
+func otpRequestPurpose(purpose string) string {
+	return "otp_" + purpose
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEmailOTPRepository - мок хранилища кодов из писем
+type MockEmailOTPRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailOTPRepository) CreateEmailOTP(ctx context.Context, otp *models.EmailOTP) error {
+	args := m.Called(ctx, otp)
+	return args.Error(0)
+}
+
+func (m *MockEmailOTPRepository) AttemptEmailOTP(ctx context.Context, userID uuid.UUID, purpose string, maxAttempts int) (*models.EmailOTP, error) {
+	args := m.Called(ctx, userID, purpose, maxAttempts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailOTP), args.Error(1)
+}
+
+func (m *MockEmailOTPRepository) DeleteEmailOTP(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+const testOTPMaxAttempts = 5
+
+func newTestEmailOTPService(userRepo *MockUserRepository, otpRepo *MockEmailOTPRepository, requests *MockEmailRequestRepository, mailer *MockMailer, tokens *MockAuthenticator, users *MockUserCacheInvalidator) *EmailOTPService {
+	return NewEmailOTPService(userRepo, otpRepo, requests, mailer, tokens, users, testTokenSecret, 10*time.Minute, testOTPMaxAttempts, testEmailRateLimit)
+}
+
+// newStoredOTP возвращает сохраненный код пользователя, как его отдал бы репозиторий
+func newStoredOTP(user *models.User, purpose, code string) *models.EmailOTP {
+	return &models.EmailOTP{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		CodeHash:  auth.HashOTPCode([]byte(testTokenSecret), purpose, user.ID.String(), code),
+		Email:     user.Email,
+		Attempts:  1,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+}
+
+func TestEmailOTPService_RequestLoginCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockOTPRepo := new(MockEmailOTPRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	mockMailer := new(MockMailer)
+	otpService := newTestEmailOTPService(mockUserRepo, mockOTPRepo, mockRequests, mockMailer, new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	user := newUnverifiedUser(t)
+	mockRequests.On("AllowEmailRequest", mock.Anything, user.Email, "otp_login", 5, mock.Anything).Return(true, nil)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	var stored *models.EmailOTP
+	mockOTPRepo.On("CreateEmailOTP", mock.Anything, mock.MatchedBy(func(otp *models.EmailOTP) bool {
+		stored = otp
+		return otp.UserID == user.ID && otp.Purpose == models.EmailOTPLogin && otp.Email == user.Email
+	})).Return(nil)
+
+	var sentCode string
+	mockMailer.On("SendOTPEmailAsync", user.Email, mock.MatchedBy(func(code string) bool {
+		sentCode = code
+		return len(code) == auth.OTPDigits
+	}), 10*time.Minute).Return()
+
+	// Act
+	err := otpService.RequestLoginCode(context.Background(), "Test@Example.com")
+
+	// Assert: в БД только хеш отправленного кода
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.NotEqual(t, sentCode, stored.CodeHash)
+	assert.True(t, auth.CheckOTPCode([]byte(testTokenSecret), models.EmailOTPLogin, user.ID.String(), sentCode, stored.CodeHash))
+	mockMailer.AssertExpectations(t)
+}
+
+func TestEmailOTPService_RequestLoginCode_UnknownEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	mockMailer := new(MockMailer)
+	otpService := newTestEmailOTPService(mockUserRepo, new(MockEmailOTPRepository), mockRequests, mockMailer, new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	mockRequests.On("AllowEmailRequest", mock.Anything, "unknown@example.com", "otp_login", 5, mock.Anything).Return(true, nil)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return(nil, postgres.ErrUserNotFound)
+
+	// Act
+	err := otpService.RequestLoginCode(context.Background(), "unknown@example.com")
+
+	// Assert: ответ такой же, как для зарегистрированного адреса
+	assert.NoError(t, err)
+	mockMailer.AssertNotCalled(t, "SendOTPEmailAsync", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailOTPService_VerifyCode(t *testing.T) {
+	user := newUnverifiedUser(t)
+
+	tests := []struct {
+		name       string
+		stored     *models.EmailOTP
+		attemptErr error
+		code       string
+		wantErr    error
+	}{
+		{"valid code", newStoredOTP(user, "confirm_action", "123456"), nil, "123456", nil},
+		{"wrong code", newStoredOTP(user, "confirm_action", "123456"), nil, "654321", ErrInvalidOTP},
+		{"attempts exhausted", nil, postgres.ErrEmailOTPNotFound, "123456", ErrInvalidOTP},
+		{"expired", func() *models.EmailOTP {
+			otp := newStoredOTP(user, "confirm_action", "123456")
+			otp.ExpiresAt = time.Now().Add(-time.Second)
+			return otp
+		}(), nil, "123456", ErrInvalidOTP},
+		{"sent to previous email", func() *models.EmailOTP {
+			otp := newStoredOTP(user, "confirm_action", "123456")
+			otp.Email = "old@example.com"
+			return otp
+		}(), nil, "123456", ErrInvalidOTP},
+		{"code of another purpose", newStoredOTP(user, models.EmailOTPLogin, "123456"), nil, "123456", ErrInvalidOTP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockOTPRepo := new(MockEmailOTPRepository)
+			otpService := newTestEmailOTPService(new(MockUserRepository), mockOTPRepo, new(MockEmailRequestRepository), new(MockMailer), new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+			if tt.stored != nil {
+				mockOTPRepo.On("AttemptEmailOTP", mock.Anything, user.ID, "confirm_action", testOTPMaxAttempts).Return(tt.stored, nil)
+				mockOTPRepo.On("DeleteEmailOTP", mock.Anything, tt.stored.ID).Return(nil)
+			} else {
+				mockOTPRepo.On("AttemptEmailOTP", mock.Anything, user.ID, "confirm_action", testOTPMaxAttempts).Return(nil, tt.attemptErr)
+			}
+
+			// Act
+			err := otpService.VerifyCode(context.Background(), user, "confirm_action", tt.code)
+
+			// Assert: код гасится только после успешной проверки
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				mockOTPRepo.AssertCalled(t, "DeleteEmailOTP", mock.Anything, tt.stored.ID)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockOTPRepo.AssertNotCalled(t, "DeleteEmailOTP", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestEmailOTPService_VerifyCode_AlreadyUsed(t *testing.T) {
+	// Arrange
+	mockOTPRepo := new(MockEmailOTPRepository)
+	otpService := newTestEmailOTPService(new(MockUserRepository), mockOTPRepo, new(MockEmailRequestRepository), new(MockMailer), new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	user := newUnverifiedUser(t)
+	stored := newStoredOTP(user, models.EmailOTPLogin, "123456")
+
+	// Параллельный запрос с тем же кодом успел удалить его первым
+	mockOTPRepo.On("AttemptEmailOTP", mock.Anything, user.ID, models.EmailOTPLogin, testOTPMaxAttempts).Return(stored, nil)
+	mockOTPRepo.On("DeleteEmailOTP", mock.Anything, stored.ID).Return(postgres.ErrEmailOTPNotFound)
+
+	// Act
+	err := otpService.VerifyCode(context.Background(), user, models.EmailOTPLogin, "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+}
+
+func TestEmailOTPService_LoginWithCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockOTPRepo := new(MockEmailOTPRepository)
+	mockTokens := new(MockAuthenticator)
+	otpService := newTestEmailOTPService(mockUserRepo, mockOTPRepo, new(MockEmailRequestRepository), new(MockMailer), mockTokens, new(MockUserCacheInvalidator))
+
+	verifiedAt := time.Now()
+	user := &models.User{ID: uuid.New(), Email: "test@example.com", EmailVerifiedAt: &verifiedAt}
+	stored := newStoredOTP(user, models.EmailOTPLogin, "042042")
+	expected := &AuthResponse{User: user, Token: "access", RefreshToken: "refresh"}
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockOTPRepo.On("AttemptEmailOTP", mock.Anything, user.ID, models.EmailOTPLogin, testOTPMaxAttempts).Return(stored, nil)
+	mockOTPRepo.On("DeleteEmailOTP", mock.Anything, stored.ID).Return(nil)
+	mockTokens.On("CompleteLogin", mock.Anything, user).Return(expected, nil)
+
+	// Act
+	result, err := otpService.LoginWithCode(context.Background(), "TEST@example.com", "042042")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockUserRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailOTPService_LoginWithCode_UnknownEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockOTPRepo := new(MockEmailOTPRepository)
+	otpService := newTestEmailOTPService(mockUserRepo, mockOTPRepo, new(MockEmailRequestRepository), new(MockMailer), new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return(nil, postgres.ErrUserNotFound)
+
+	// Act
+	result, err := otpService.LoginWithCode(context.Background(), "unknown@example.com", "123456")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+	assert.Nil(t, result)
+	mockOTPRepo.AssertNotCalled(t, "AttemptEmailOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailOTPService_RequestReauthCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockOTPRepo := new(MockEmailOTPRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	mockMailer := new(MockMailer)
+	otpService := newTestEmailOTPService(mockUserRepo, mockOTPRepo, mockRequests, mockMailer, new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	passwordless := &models.User{ID: uuid.New(), Email: "passwordless@example.com"}
+	withPassword := &models.User{ID: uuid.New(), Email: "test@example.com", PasswordHash: "hash"}
+
+	mockUserRepo.On("GetUserByID", mock.Anything, passwordless.ID.String()).Return(passwordless, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, withPassword.ID.String()).Return(withPassword, nil)
+	mockRequests.On("AllowEmailRequest", mock.Anything, passwordless.Email, otpRequestPurpose(models.EmailOTPReauth), testEmailRateLimit.Limit, mock.Anything).Return(true, nil)
+	mockOTPRepo.On("CreateEmailOTP", mock.Anything, mock.MatchedBy(func(otp *models.EmailOTP) bool {
+		return otp.UserID == passwordless.ID && otp.Purpose == models.EmailOTPReauth
+	})).Return(nil)
+	mockMailer.On("SendOTPEmailAsync", passwordless.Email, mock.AnythingOfType("string"), 10*time.Minute).Return()
+
+	// Act & Assert: аккаунт с паролем подтверждает действия паролем
+	require.NoError(t, otpService.RequestReauthCode(context.Background(), passwordless.ID.String()))
+	assert.ErrorIs(t, otpService.RequestReauthCode(context.Background(), withPassword.ID.String()), ErrPasswordReauthRequired)
+
+	mockOTPRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+}
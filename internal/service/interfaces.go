@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"auth-service/internal/auth"
 	"auth-service/internal/models"
+	"github.com/google/uuid"
 )
 
 // UserRepository интерфейс для работы с пользователями
@@ -12,11 +14,207 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, req *models.CreateUserRequest, passwordHash string) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	UserExists(ctx context.Context, email string) (bool, error)
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) error
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) (int, error)
+	UpgradePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error
+	UpdateEmail(ctx context.Context, userID uuid.UUID, email string) (int, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, purgeAfter time.Time, reason string) error
+	CancelDeletion(ctx context.Context, userID uuid.UUID) (models.UserStatus, error)
+	ChangeStatus(ctx context.Context, userID uuid.UUID, status models.UserStatus, reason string) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, profile *models.Profile) (*models.User, error)
+	RecordFailedLogin(ctx context.Context, userID uuid.UUID, threshold int, lockFor, maxLockFor time.Duration) (*time.Time, error)
+	ResetFailedLogins(ctx context.Context, userID uuid.UUID) error
+	ListUsers(ctx context.Context, filter *models.UserListFilter) ([]models.User, error)
+	ListStatusHistory(ctx context.Context, userID uuid.UUID) ([]models.UserStatusChange, error)
+	RevokeTokens(ctx context.Context, userID uuid.UUID) (int, error)
+	RequirePasswordReset(ctx context.Context, userID uuid.UUID) error
+	SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, secret string, step int64) error
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+}
+
+// TokenStateRepository интерфейс чтения текущего поколения токенов и статуса пользователя
+// вместе с отметкой, находится ли jti в denylist
+type TokenStateRepository interface {
+	GetTokenState(ctx context.Context, userID, jti string) (generation int, status models.UserStatus, revoked bool, err error)
+}
+
+// UserTokenRepository интерфейс для хранения одноразовых токенов из писем
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	GetUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	ConsumeBoundUserToken(ctx context.Context, purpose, tokenHash, bindingHash string) (*models.UserToken, error)
+	LastUserTokenCreatedAt(ctx context.Context, userID uuid.UUID, purpose string) (*time.Time, error)
+	DeleteUserTokens(ctx context.Context, userID uuid.UUID, purpose string) error
+	ListUserTokens(ctx context.Context, userID uuid.UUID) ([]models.UserToken, error)
+}
+
+// DataExportRepository интерфейс хранения собранных выгрузок до скачивания по ссылке
+type DataExportRepository interface {
+	SaveDataExport(ctx context.Context, tokenID, userID uuid.UUID, data []byte, expiresAt time.Time) error
+	TakeDataExport(ctx context.Context, tokenID uuid.UUID) ([]byte, error)
+	DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EmailOTPRepository интерфейс для хранения одноразовых кодов из писем
+type EmailOTPRepository interface {
+	CreateEmailOTP(ctx context.Context, otp *models.EmailOTP) error
+	AttemptEmailOTP(ctx context.Context, userID uuid.UUID, purpose string, maxAttempts int) (*models.EmailOTP, error)
+	DeleteEmailOTP(ctx context.Context, id uuid.UUID) error
+}
+
+// EmailRequestRepository интерфейс учета запросов писем для ограничения частоты
+type EmailRequestRepository interface {
+	AllowEmailRequest(ctx context.Context, email, purpose string, limit int, since time.Time) (bool, error)
+}
+
+// VerificationMailer интерфейс отправки писем с подтверждением адреса
+type VerificationMailer interface {
+	SendVerificationEmailAsync(email, link string)
+}
+
+// PasswordResetMailer интерфейс отправки писем со ссылкой для сброса пароля
+type PasswordResetMailer interface {
+	SendPasswordResetEmailAsync(email, link string)
+}
+
+// MagicLinkMailer интерфейс отправки писем со ссылкой для входа
+type MagicLinkMailer interface {
+	SendMagicLinkEmailAsync(email, link string)
+}
+
+// OTPMailer интерфейс отправки писем с одноразовым кодом
+type OTPMailer interface {
+	SendOTPEmailAsync(email, code string, ttl time.Duration)
+}
+
+// EmailChangeMailer интерфейс отправки писем о смене email
+type EmailChangeMailer interface {
+	SendEmailChangeConfirmationAsync(email, link string)
+	SendEmailChangeNoticeAsync(email, newEmail, undoLink string)
+}
+
+// DataExportMailer интерфейс отправки писем со ссылкой на выгрузку данных
+// Письмо отправляется синхронно: выгрузка и так собирается в фоне
+type DataExportMailer interface {
+	SendDataExportEmail(email, link string) error
+}
+
+// SecurityMailer интерфейс отправки уведомлений о безопасности аккаунта
+type SecurityMailer interface {
+	SendAccountLockedEmailAsync(email string, lockedUntil time.Time)
+}
+
+// LoginLockout интерфейс учета неудачных попыток входа
+type LoginLockout interface {
+	RecordFailure(ctx context.Context, user *models.User) (locked bool, err error)
+	Reset(ctx context.Context, user *models.User) error
+}
+
+// EmailCodeVerifier интерфейс проверки одноразового кода из письма
+type EmailCodeVerifier interface {
+	VerifyCode(ctx context.Context, user *models.User, purpose, code string) error
+}
+
+// AccountManager интерфейс смены статуса и удаления аккаунтов
+type AccountManager interface {
+	SuspendAccount(ctx context.Context, userID, reason string) (*models.User, error)
+	UnsuspendAccount(ctx context.Context, userID, reason string) (*models.User, error)
+	ScheduleDeletion(ctx context.Context, user *models.User, reason string) (time.Time, error)
+}
+
+// PasswordResetSender интерфейс отправки ссылки для сброса пароля
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, user *models.User) error
+}
+
+// UserCacheInvalidator интерфейс сброса кеша пользователя после изменения его данных
+type UserCacheInvalidator interface {
+	InvalidateUser(email string)
+}
+
+// ProfileProvider интерфейс получения профиля пользователя
+type ProfileProvider interface {
+	GetProfile(ctx context.Context, userID string) (*models.User, error)
+}
+
+// EmailVerifier интерфейс отправки ссылки для подтверждения адреса
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, user *models.User) error
+}
+
+// RefreshTokenRepository интерфейс для хранения refresh токенов
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	ListUserRefreshTokens(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error)
+}
+
+// RevokedTokenRepository интерфейс для хранения denylist отозванных токенов
+type RevokedTokenRepository interface {
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TokenRevoker интерфейс для отзыва access токенов
+type TokenRevoker interface {
+	Revoke(ctx context.Context, claims *auth.Claims) error
+}
+
+// OAuthRepository интерфейс для работы с OAuth клиентами и кодами авторизации
+type OAuthRepository interface {
+	GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error)
+}
+
+// Authenticator интерфейс проверки учетных данных и выдачи токенов пользователю
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (*models.User, error)
+	IssueClientTokens(ctx context.Context, user *models.User, clientID string, scopes []string) (*AuthResponse, error)
+	CompleteLogin(ctx context.Context, user *models.User) (*AuthResponse, error)
+	RefreshClient(ctx context.Context, clientID, refreshToken string) (*AuthResponse, error)
+}
+
+// LoginCompleter интерфейс завершения входа пользователя, подтвердившего первый фактор
+type LoginCompleter interface {
+	CompleteLogin(ctx context.Context, user *models.User) (*AuthResponse, error)
+}
+
+// MFAChallenger интерфейс второго шага входа для пользователей с включенной TOTP
+type MFAChallenger interface {
+	Challenge(ctx context.Context, user *models.User) (string, time.Time, error)
+	VerifyChallenge(ctx context.Context, token, code string) (*models.User, error)
+}
+
+// TOTPVerifier интерфейс проверки кода TOTP пользователя
+type TOTPVerifier interface {
+	VerifyTOTP(ctx context.Context, userID, code string) (*models.User, error)
+}
+
+// RevocationChecker интерфейс проверки отзыва access токенов
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error)
+}
+
+// ClientAuthenticator интерфейс аутентификации OAuth клиентов
+type ClientAuthenticator interface {
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error)
 }
 
 // JWTService интерфейс для работы с JWT токенами
 type JWTService interface {
-	GenerateToken(userID, email string) (string, error)
+	GenerateToken(userID, email string, generation int, scopes []string) (string, error)
+	GenerateClientToken(clientID string, scopes []string) (string, error)
 	ValidateToken(tokenString string) (*auth.Claims, error)
+	Expiration() time.Duration
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"auth-service/internal/auth"
+)
+
+// IntrospectionResponse - ответ introspection endpoint (RFC 7662, раздел 2.2).
+// Для неактивного токена заполняется только Active.
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
+
+// IntrospectionService проверяет access токены по запросу сервисов,
+// которые не умеют валидировать JWT локально
+type IntrospectionService struct {
+	clients     ClientAuthenticator
+	jwtService  JWTService
+	revocations RevocationChecker
+}
+
+func NewIntrospectionService(clients ClientAuthenticator, jwtService JWTService, revocations RevocationChecker) *IntrospectionService {
+	return &IntrospectionService{
+		clients:     clients,
+		jwtService:  jwtService,
+		revocations: revocations,
+	}
+}
+
+// Introspect аутентифицирует вызывающий сервис и возвращает состояние токена
+func (s *IntrospectionService) Introspect(ctx context.Context, clientID, clientSecret, token string) (*IntrospectionResponse, error) {
+	client, err := s.clients.AuthenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	// Спрашивать о токенах могут только конфиденциальные клиенты
+	if !client.IsConfidential() {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	if strings.TrimSpace(token) == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "token is required")
+	}
+
+	inactive := &IntrospectionResponse{Active: false}
+
+	// Причина неактивности (истек, подделан, отозван) наружу не раскрывается
+	claims, err := s.jwtService.ValidateToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	revoked, err := s.revocations.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	return activeIntrospection(claims), nil
+}
+
+func activeIntrospection(claims *auth.Claims) *IntrospectionResponse {
+	response := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Email,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.Nbf = claims.NotBefore.Unix()
+	}
+
+	return response
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockClientAuthenticator - мок аутентификации OAuth клиентов
+type MockClientAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockClientAuthenticator) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	args := m.Called(ctx, clientID, clientSecret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+// MockRevocationChecker - мок проверки отзыва токенов
+type MockRevocationChecker struct {
+	mock.Mock
+}
+
+func (m *MockRevocationChecker) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	args := m.Called(ctx, claims)
+	return args.Bool(0), args.Error(1)
+}
+
+var confidentialClient = &models.OAuthClient{ClientID: "legacy", SecretHash: "hash"}
+
+func TestIntrospectionService_ActiveToken(t *testing.T) {
+	// Arrange
+	mockClients := new(MockClientAuthenticator)
+	mockJWTService := new(MockJWTService)
+	mockRevocations := new(MockRevocationChecker)
+	introspectionService := NewIntrospectionService(mockClients, mockJWTService, mockRevocations)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims := &auth.Claims{
+		UserID: "user-1",
+		Email:  "test@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ID:        "jti-1",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	// Настраиваем моки
+	mockClients.On("AuthenticateClient", mock.Anything, "legacy", "secret").Return(confidentialClient, nil)
+	mockJWTService.On("ValidateToken", "access-token").Return(claims, nil)
+	mockRevocations.On("IsRevoked", mock.Anything, claims).Return(false, nil)
+
+	// Act
+	result, err := introspectionService.Introspect(context.Background(), "legacy", "secret", "access-token")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, result.Active)
+	assert.Equal(t, "user-1", result.Sub)
+	assert.Equal(t, "test@example.com", result.Username)
+	assert.Equal(t, expiresAt.Unix(), result.Exp)
+}
+
+func TestIntrospectionService_InactiveTokensRevealNothing(t *testing.T) {
+	// Arrange
+	mockClients := new(MockClientAuthenticator)
+	mockJWTService := new(MockJWTService)
+	mockRevocations := new(MockRevocationChecker)
+	introspectionService := NewIntrospectionService(mockClients, mockJWTService, mockRevocations)
+
+	revokedClaims := &auth.Claims{UserID: "user-1", Email: "test@example.com"}
+
+	// Настраиваем моки
+	mockClients.On("AuthenticateClient", mock.Anything, "legacy", "secret").Return(confidentialClient, nil)
+	mockJWTService.On("ValidateToken", "expired-token").Return(nil, errors.New("token is expired"))
+	mockJWTService.On("ValidateToken", "revoked-token").Return(revokedClaims, nil)
+	mockRevocations.On("IsRevoked", mock.Anything, revokedClaims).Return(true, nil)
+
+	for _, token := range []string{"expired-token", "revoked-token"} {
+		// Act
+		result, err := introspectionService.Introspect(context.Background(), "legacy", "secret", token)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, &IntrospectionResponse{Active: false}, result)
+	}
+}
+
+func TestIntrospectionService_RejectsPublicClient(t *testing.T) {
+	// Arrange
+	mockClients := new(MockClientAuthenticator)
+	introspectionService := NewIntrospectionService(mockClients, new(MockJWTService), new(MockRevocationChecker))
+
+	mockClients.On("AuthenticateClient", mock.Anything, "spa", "").Return(testClient, nil)
+
+	// Act
+	result, err := introspectionService.Introspect(context.Background(), "spa", "", "access-token")
+
+	// Assert
+	var oauthErr *OAuthError
+	assert.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, OAuthErrInvalidClient, oauthErr.Code)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/models"
+)
+
+// ErrAccountLocked - вход временно заблокирован после серии неудачных попыток
+var ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
+
+// LockoutPolicy - параметры блокировки входа. Threshold = 0 выключает блокировку.
+type LockoutPolicy struct {
+	// Неудачных попыток подряд до блокировки
+	Threshold int
+	// Длительность первой блокировки; каждая следующая подряд вдвое дольше
+	Duration time.Duration
+	// Верхняя граница длительности блокировки
+	MaxDuration time.Duration
+}
+
+// LockoutService блокирует вход в аккаунт после серии неудачных попыток
+// и уведомляет владельца о блокировке
+type LockoutService struct {
+	userRepo UserRepository
+	mailer   SecurityMailer
+	policy   LockoutPolicy
+}
+
+func NewLockoutService(userRepo UserRepository, mailer SecurityMailer, policy LockoutPolicy) *LockoutService {
+	return &LockoutService{
+		userRepo: userRepo,
+		mailer:   mailer,
+		policy:   policy,
+	}
+}
+
+// RecordFailure учитывает неудачную попытку входа и сообщает, заблокирован ли теперь аккаунт
+func (s *LockoutService) RecordFailure(ctx context.Context, user *models.User) (bool, error) {
+	if s.policy.Threshold <= 0 {
+		return false, nil
+	}
+
+	lockedUntil, err := s.userRepo.RecordFailedLogin(ctx, user.ID, s.policy.Threshold, s.policy.Duration, s.policy.MaxDuration)
+	if err != nil {
+		return false, err
+	}
+	if lockedUntil == nil {
+		return false, nil
+	}
+
+	s.mailer.SendAccountLockedEmailAsync(user.Email, *lockedUntil)
+	log.Printf("🔒 Account %s locked until %s after %d failed logins", user.ID, lockedUntil.Format(time.RFC3339), s.policy.Threshold)
+
+	return true, nil
+}
+
+// Reset обнуляет счетчики неудачных входов и снимает блокировку
+func (s *LockoutService) Reset(ctx context.Context, user *models.User) error {
+	return s.userRepo.ResetFailedLogins(ctx, user.ID)
+}
+
+// checkPasswordWithLockout повторно проверяет пароль пользователя по тем же правилам, что и вход:
+// во время блокировки пароль не проверяется, неверный пароль учитывается как неудачная попытка,
+// верный обнуляет счетчик. При неверном пароле возвращает mismatch.
+func checkPasswordWithLockout(ctx context.Context, lockout LoginLockout, users UserCacheInvalidator, user *models.User, password string, mismatch error) error {
+	// Подбирать нечего - попытку не учитываем, как и при входе
+	if !user.HasPassword() {
+		return mismatch
+	}
+	if user.IsLocked() {
+		return ErrAccountLocked
+	}
+
+	if err := checkPassword(user.PasswordHash, password); err != nil {
+		locked, err := lockout.RecordFailure(ctx, user)
+		// Счетчики в БД изменились - в кеше пользователь устарел
+		users.InvalidateUser(user.Email)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrAccountLocked
+		}
+		return mismatch
+	}
+
+	// С включенной TOTP счетчик обнуляет только верный код
+	if !user.TOTPEnabled() && (user.FailedLoginAttempts > 0 || user.LockoutCount > 0) {
+		if err := lockout.Reset(ctx, user); err != nil {
+			return err
+		}
+		users.InvalidateUser(user.Email)
+	}
+	return nil
+}
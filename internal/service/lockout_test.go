@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_Login_WrongPasswordLocksAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil).Once()
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(true, nil).Once()
+
+	req := &models.LoginRequest{Email: user.Email, Password: "wrong-password"}
+
+	// Act & Assert: последняя попытка до порога блокирует аккаунт
+	_, err := authService.Login(context.Background(), req)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authService.Login(context.Background(), req)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// Счетчик изменился в БД, поэтому пользователь каждый раз читается заново, а не из кеша
+	mockUserRepo.AssertNumberOfCalls(t, "GetUserByEmail", 2)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestAuthService_Login_LockedAccount(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	lockedUntil := time.Now().Add(time.Minute)
+	user.LockedUntil = &lockedUntil
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act: даже верный пароль не принимается, пока действует блокировка
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.Nil(t, result)
+	mockLockout.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_SuccessResetsFailedAttempts(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := newUnverifiedUser(t)
+	expiredLock := time.Now().Add(-time.Minute)
+	user.FailedLoginAttempts = 2
+	user.LockoutCount = 1
+	user.LockedUntil = &expiredLock
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockLockout.On("Reset", mock.Anything, user).Return(nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act
+	_, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	require.NoError(t, err)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestLockoutService_RecordFailure(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	policy := LockoutPolicy{Threshold: 5, Duration: 15 * time.Minute, MaxDuration: 24 * time.Hour}
+	lockoutService := NewLockoutService(mockUserRepo, mockMailer, policy)
+
+	user := newUnverifiedUser(t)
+	lockedUntil := time.Now().Add(15 * time.Minute)
+	mockUserRepo.On("RecordFailedLogin", mock.Anything, user.ID, 5, 15*time.Minute, 24*time.Hour).Return(nil, nil).Once()
+	mockUserRepo.On("RecordFailedLogin", mock.Anything, user.ID, 5, 15*time.Minute, 24*time.Hour).Return(&lockedUntil, nil).Once()
+	mockMailer.On("SendAccountLockedEmailAsync", user.Email, lockedUntil).Return().Once()
+
+	// Act & Assert: письмо уходит только при блокировке
+	locked, err := lockoutService.RecordFailure(context.Background(), user)
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	locked, err = lockoutService.RecordFailure(context.Background(), user)
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	mockMailer.AssertExpectations(t)
+}
+
+func TestLockoutService_Disabled(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	lockoutService := NewLockoutService(mockUserRepo, new(MockMailer), LockoutPolicy{})
+
+	locked, err := lockoutService.RecordFailure(context.Background(), newUnverifiedUser(t))
+
+	require.NoError(t, err)
+	assert.False(t, locked)
+	mockUserRepo.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// ErrInvalidMagicLink - ссылка поддельная, использованная, истекшая или открыта не на том устройстве
+var ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+
+// MagicLinkService - вход без пароля по одноразовой ссылке из письма.
+// Ссылка привязана к устройству, которое ее запросило: вместе с запросом выдается
+// секрет устройства (binding), без которого ссылка не принимается.
+type MagicLinkService struct {
+	userRepo  UserRepository
+	tokenRepo UserTokenRepository
+	requests  EmailRequestRepository
+	mailer    MagicLinkMailer
+	tokens    LoginCompleter
+	users     UserCacheInvalidator
+	secret    []byte
+	linkURL   string
+	linkTTL   time.Duration
+	rateLimit EmailRateLimit
+}
+
+func NewMagicLinkService(userRepo UserRepository, tokenRepo UserTokenRepository, requests EmailRequestRepository, mailer MagicLinkMailer, tokens LoginCompleter, users UserCacheInvalidator, secret, linkURL string, linkTTL time.Duration, rateLimit EmailRateLimit) *MagicLinkService {
+	return &MagicLinkService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		requests:  requests,
+		mailer:    mailer,
+		tokens:    tokens,
+		users:     users,
+		secret:    []byte(secret),
+		linkURL:   linkURL,
+		linkTTL:   linkTTL,
+		rateLimit: rateLimit,
+	}
+}
+
+// RequestMagicLink отправляет ссылку для входа и возвращает секрет устройства,
+// который нужно предъявить вместе с ссылкой. Секрет возвращается и для неизвестных,
+// удаленных и приостановленных аккаунтов, которым письмо не отправляется,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *MagicLinkService) RequestMagicLink(ctx context.Context, email string) (string, error) {
+	binding, bindingHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+
+	email, err = NormalizeEmail(email)
+	if err != nil {
+		return binding, nil
+	}
+
+	if err := allowEmailRequest(ctx, s.requests, s.rateLimit, email, models.UserTokenMagicLink); err != nil {
+		return "", err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return binding, nil
+		}
+		return "", err
+	}
+	if user.IsDeleted() || user.IsSuspended() {
+		return binding, nil
+	}
+
+	token, err := issueBoundUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenMagicLink, user.ID, user.Email, bindingHash, s.linkTTL)
+	if err != nil {
+		return "", err
+	}
+
+	s.mailer.SendMagicLinkEmailAsync(user.Email, s.linkURL+"?token="+url.QueryEscape(token))
+	log.Printf("📨 Magic link email queued for: %s", user.Email)
+
+	return binding, nil
+}
+
+// VerifyMagicLink обменивает ссылку из письма на пару токенов, как при входе по паролю.
+// Ссылка одноразовая и принимается только с секретом устройства, которое ее запросило.
+// Переход по ссылке подтверждает адрес. Блокировка после неудачных попыток входа
+// ссылку не касается: она защищает от подбора пароля.
+func (s *MagicLinkService) VerifyMagicLink(ctx context.Context, token, binding string) (*AuthResponse, error) {
+	if binding == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	stored, err := consumeBoundUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenMagicLink, token, auth.HashOpaqueToken(binding))
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+	// Письмо ушло на прежний адрес - после смены email ссылка не действует
+	if user.Email != stored.Email {
+		return nil, ErrInvalidMagicLink
+	}
+
+	if user, err = completeEmailLogin(ctx, s.userRepo, s.users, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔗 Magic link login for user %s", user.ID)
+	return s.tokens.CompleteLogin(ctx, user)
+}
+
+// completeEmailLogin проверяет, можно ли войти пользователю, который подтвердил
+// владение адресом ссылкой или кодом из письма, и отмечает адрес подтвержденным.
+// Возвращает пользователя с актуальными данными.
+func completeEmailLogin(ctx context.Context, userRepo UserRepository, users UserCacheInvalidator, user *models.User) (*models.User, error) {
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
+
+	if user.IsEmailVerified() {
+		return user, nil
+	}
+
+	if err := userRepo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+		return nil, err
+	}
+	users.InvalidateUser(user.Email)
+
+	return userRepo.GetUserByID(ctx, user.ID.String())
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEmailRequestRepository - мок учета запросов писем
+type MockEmailRequestRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailRequestRepository) AllowEmailRequest(ctx context.Context, email, purpose string, limit int, since time.Time) (bool, error) {
+	args := m.Called(ctx, email, purpose, limit, since)
+	return args.Bool(0), args.Error(1)
+}
+
+var testEmailRateLimit = EmailRateLimit{Limit: 5, Window: time.Hour}
+
+func newTestMagicLinkService(userRepo *MockUserRepository, tokenRepo *MockUserTokenRepository, requests *MockEmailRequestRepository, mailer *MockMailer, tokens *MockAuthenticator, users *MockUserCacheInvalidator) *MagicLinkService {
+	return NewMagicLinkService(userRepo, tokenRepo, requests, mailer, tokens, users, testTokenSecret, "https://auth.example.com/auth/magic-link/verify", 15*time.Minute, testEmailRateLimit)
+}
+
+func TestMagicLinkService_RequestMagicLink(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	mockMailer := new(MockMailer)
+	magicLinkService := newTestMagicLinkService(mockUserRepo, mockTokenRepo, mockRequests, mockMailer, new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	user := newUnverifiedUser(t)
+	mockRequests.On("AllowEmailRequest", mock.Anything, user.Email, models.UserTokenMagicLink, 5, mock.Anything).Return(true, nil)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	var stored *models.UserToken
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		stored = token
+		return token.UserID == user.ID && token.Purpose == models.UserTokenMagicLink
+	})).Return(nil)
+	mockMailer.On("SendMagicLinkEmailAsync", user.Email, mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, "https://auth.example.com/auth/magic-link/verify?token=")
+	})).Return()
+
+	// Act
+	binding, err := magicLinkService.RequestMagicLink(context.Background(), "  Test@Example.com ")
+
+	// Assert: ссылка привязана к секрету устройства из ответа
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, auth.HashOpaqueToken(binding), stored.BindingHash)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestMagicLinkService_RequestMagicLink_UnknownEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	mockMailer := new(MockMailer)
+	magicLinkService := newTestMagicLinkService(mockUserRepo, new(MockUserTokenRepository), mockRequests, mockMailer, new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	mockRequests.On("AllowEmailRequest", mock.Anything, "unknown@example.com", models.UserTokenMagicLink, 5, mock.Anything).Return(true, nil)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return(nil, postgres.ErrUserNotFound)
+
+	// Act
+	binding, err := magicLinkService.RequestMagicLink(context.Background(), "unknown@example.com")
+
+	// Assert: ответ такой же, как для зарегистрированного адреса
+	assert.NoError(t, err)
+	assert.NotEmpty(t, binding)
+	mockMailer.AssertNotCalled(t, "SendMagicLinkEmailAsync", mock.Anything, mock.Anything)
+}
+
+func TestMagicLinkService_RequestMagicLink_RateLimited(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRequests := new(MockEmailRequestRepository)
+	magicLinkService := newTestMagicLinkService(mockUserRepo, new(MockUserTokenRepository), mockRequests, new(MockMailer), new(MockAuthenticator), new(MockUserCacheInvalidator))
+
+	mockRequests.On("AllowEmailRequest", mock.Anything, "test@example.com", models.UserTokenMagicLink, 5, mock.Anything).Return(false, nil)
+
+	// Act
+	binding, err := magicLinkService.RequestMagicLink(context.Background(), "test@example.com")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTooManyEmailRequests)
+	assert.Empty(t, binding)
+	mockUserRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestMagicLinkService_VerifyMagicLink(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockTokens := new(MockAuthenticator)
+	mockUsers := new(MockUserCacheInvalidator)
+	magicLinkService := newTestMagicLinkService(mockUserRepo, mockTokenRepo, new(MockEmailRequestRepository), new(MockMailer), mockTokens, mockUsers)
+
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenMagicLink)
+	require.NoError(t, err)
+	binding, bindingHash, err := auth.NewOpaqueToken()
+	require.NoError(t, err)
+
+	// Пользователь без пароля, адрес еще не подтвержден
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	verifiedAt := time.Now()
+	verified := &models.User{ID: user.ID, Email: user.Email, EmailVerifiedAt: &verifiedAt}
+	stored := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenMagicLink,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	expected := &AuthResponse{Token: "access", RefreshToken: "refresh"}
+
+	mockTokenRepo.On("ConsumeBoundUserToken", mock.Anything, models.UserTokenMagicLink, tokenHash, bindingHash).Return(stored, nil).Once()
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil).Once()
+	mockUserRepo.On("MarkEmailVerified", mock.Anything, user.ID, user.Email).Return(nil).Once()
+	mockUsers.On("InvalidateUser", user.Email).Return().Once()
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(verified, nil).Once()
+	mockTokens.On("CompleteLogin", mock.Anything, verified).Return(expected, nil).Once()
+
+	// Act
+	result, err := magicLinkService.VerifyMagicLink(context.Background(), token, binding)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockUserRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestMagicLinkService_VerifyMagicLink_WrongDevice(t *testing.T) {
+	// Arrange
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockTokens := new(MockAuthenticator)
+	magicLinkService := newTestMagicLinkService(new(MockUserRepository), mockTokenRepo, new(MockEmailRequestRepository), new(MockMailer), mockTokens, new(MockUserCacheInvalidator))
+
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenMagicLink)
+	require.NoError(t, err)
+
+	// Ссылку открыли на другом устройстве: секрет не совпадает, токен не находится
+	mockTokenRepo.On("ConsumeBoundUserToken", mock.Anything, models.UserTokenMagicLink, tokenHash, auth.HashOpaqueToken("other-device")).Return(nil, postgres.ErrUserTokenNotFound)
+
+	// Act & Assert
+	_, err = magicLinkService.VerifyMagicLink(context.Background(), token, "other-device")
+	assert.ErrorIs(t, err, ErrInvalidMagicLink)
+
+	// Без секрета устройства ссылка отклоняется без обращения к БД
+	_, err = magicLinkService.VerifyMagicLink(context.Background(), token, "")
+	assert.ErrorIs(t, err, ErrInvalidMagicLink)
+
+	mockTokenRepo.AssertNumberOfCalls(t, "ConsumeBoundUserToken", 1)
+	mockTokens.AssertNotCalled(t, "CompleteLogin", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_PasswordlessUser(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	authService := NewAuthService(mockUserRepo, new(MockJWTService), new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, new(MockMFAChallenger), VerificationPolicyOff, time.Hour)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert: пароля нет - неудачная попытка не учитывается в блокировке
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Nil(t, result)
+	mockLockout.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+}
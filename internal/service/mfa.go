@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// Ошибки второго шага входа
+var (
+	// ErrMFARequired - у пользователя включена TOTP, а код не передан
+	ErrMFARequired     = errors.New("two-factor authentication code required")
+	ErrInvalidTOTPCode = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+)
+
+// MFAService - второй шаг входа для пользователей с включенной TOTP.
+// После проверки пароля вместо токенов выдается одноразовый MFA токен, который
+// обменивается на токены вместе с кодом из приложения-аутентификатора.
+// Неверные коды учитываются в блокировке входа так же, как неверные пароли.
+type MFAService struct {
+	userRepo     UserRepository
+	tokenRepo    UserTokenRepository
+	lockout      LoginLockout
+	box          *auth.SecretBox
+	secret       []byte
+	challengeTTL time.Duration
+}
+
+func NewMFAService(userRepo UserRepository, tokenRepo UserTokenRepository, lockout LoginLockout, box *auth.SecretBox, secret string, challengeTTL time.Duration) *MFAService {
+	return &MFAService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		lockout:      lockout,
+		box:          box,
+		secret:       []byte(secret),
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Challenge выдает MFA токен для второго шага входа и срок его действия.
+// Предыдущий неиспользованный MFA токен пользователя перестает действовать.
+func (s *MFAService) Challenge(ctx context.Context, user *models.User) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.challengeTTL)
+
+	token, err := issueUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenMFAChallenge, user.ID, user.Email, s.challengeTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// VerifyChallenge проверяет MFA токен и код из приложения и возвращает пользователя.
+// После неверного кода токен остается действительным до истечения срока, пока не
+// сработает блокировка входа; верный код гасит токен.
+func (s *MFAService) VerifyChallenge(ctx context.Context, token, code string) (*models.User, error) {
+	tokenHash, err := auth.VerifySignedToken(s.secret, models.UserTokenMFAChallenge, token)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	stored, err := s.tokenRepo.GetUserToken(ctx, models.UserTokenMFAChallenge, tokenHash)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserTokenNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.VerifyTOTP(ctx, stored.UserID.String(), code)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotEnabled) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	if _, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenMFAChallenge, token); err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// VerifyTOTP проверяет код из приложения пользователя с включенной TOTP и возвращает
+// пользователя, перечитанного из БД. Каждый код принимается один раз. Неверный код
+// учитывается как неудачная попытка входа, верный обнуляет счетчик; во время
+// блокировки код не проверяется.
+func (s *MFAService) VerifyTOTP(ctx context.Context, userID, code string) (*models.User, error) {
+	// Кеш пользователей не годится: в нем могут быть устаревшие счетчики блокировки
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, ErrTOTPNotEnabled
+		}
+		return nil, err
+	}
+	if !user.TOTPEnabled() {
+		return nil, ErrTOTPNotEnabled
+	}
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
+	secret, err := s.box.Open(user.TOTPSecret, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if ok {
+		// Повторно предъявленный код считается неверным
+		if ok, err = s.userRepo.UseTOTPStep(ctx, user.ID, step); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		locked, err := s.lockout.RecordFailure(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidTOTPCode
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockoutCount > 0 {
+		if err := s.lockout.Reset(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
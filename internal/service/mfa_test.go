@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockMFAChallenger мок для MFAChallenger
+type MockMFAChallenger struct {
+	mock.Mock
+}
+
+func (m *MockMFAChallenger) Challenge(ctx context.Context, user *models.User) (string, time.Time, error) {
+	args := m.Called(ctx, user)
+	return args.String(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockMFAChallenger) VerifyChallenge(ctx context.Context, token, code string) (*models.User, error) {
+	args := m.Called(ctx, token, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// MockTOTPVerifier мок для TOTPVerifier
+type MockTOTPVerifier struct {
+	mock.Mock
+}
+
+func (m *MockTOTPVerifier) VerifyTOTP(ctx context.Context, userID, code string) (*models.User, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// newTestSecretBox создает шифратор секретов TOTP с тестовым ключом
+func newTestSecretBox(t *testing.T) *auth.SecretBox {
+	t.Helper()
+
+	box, err := auth.NewSecretBox("mfa-key")
+	require.NoError(t, err)
+	return box
+}
+
+// newTOTPUser создает пользователя с паролем password123 и включенной TOTP.
+// Возвращает пользователя и секрет в открытом виде.
+func newTOTPUser(t *testing.T, box *auth.SecretBox) (*models.User, string) {
+	t.Helper()
+
+	user := newUnverifiedUser(t)
+
+	secret, err := auth.NewTOTPSecret()
+	require.NoError(t, err)
+	user.TOTPSecret, err = box.Seal(secret, user.ID.String())
+	require.NoError(t, err)
+
+	enabledAt := time.Now().Add(-time.Hour)
+	user.TOTPEnabledAt = &enabledAt
+	return user, secret
+}
+
+// currentTOTPCode возвращает код, который сейчас показывает приложение
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestAuthService_Login_TOTPReturnsChallenge(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockLockout := new(MockLoginLockout)
+	mockMFA := new(MockMFAChallenger)
+	authService := NewAuthService(mockUserRepo, mockJWTService, new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), mockLockout, mockMFA, VerificationPolicyOff, time.Hour)
+
+	user, _ := newTOTPUser(t, newTestSecretBox(t))
+	user.FailedLoginAttempts = 2
+	expiresAt := time.Now().Add(5 * time.Minute)
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockMFA.On("Challenge", mock.Anything, user).Return("mfa-token", expiresAt, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert: вместо токенов - MFA токен
+	require.NoError(t, err)
+	assert.Equal(t, "mfa-token", result.MFAToken)
+	assert.Equal(t, expiresAt, *result.MFATokenExpiresAt)
+	assert.Empty(t, result.Token)
+	assert.Empty(t, result.RefreshToken)
+
+	// Верный пароль не обнуляет счетчик неудачных попыток, пока не введен код
+	mockLockout.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockMFA.AssertExpectations(t)
+}
+
+func TestAuthService_VerifyMFA_IssuesTokens(t *testing.T) {
+	// Arrange
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockMFA := new(MockMFAChallenger)
+	authService := NewAuthService(new(MockUserRepository), mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), mockMFA, VerificationPolicyOff, time.Hour)
+
+	user, _ := newTOTPUser(t, newTestSecretBox(t))
+
+	mockMFA.On("VerifyChallenge", mock.Anything, "mfa-token", "123456").Return(user, nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, firstPartyScopes).Return("jwt-token", nil)
+	mockJWTService.On("Expiration").Return(15 * time.Minute)
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	// Act
+	result, err := authService.VerifyMFA(context.Background(), "mfa-token", "123456")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "jwt-token", result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.Empty(t, result.MFAToken)
+}
+
+func TestAuthService_VerifyMFA_PasswordResetRequiredAfterFirstStep(t *testing.T) {
+	// Arrange
+	mockJWTService := new(MockJWTService)
+	mockMFA := new(MockMFAChallenger)
+	authService := NewAuthService(new(MockUserRepository), mockJWTService, new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), mockMFA, VerificationPolicyOff, time.Hour)
+
+	user, _ := newTOTPUser(t, newTestSecretBox(t))
+	user.PasswordResetRequired = true
+
+	mockMFA.On("VerifyChallenge", mock.Anything, "mfa-token", "123456").Return(user, nil)
+
+	// Act
+	result, err := authService.VerifyMFA(context.Background(), "mfa-token", "123456")
+
+	// Assert: администратор потребовал сменить пароль между шагами входа
+	assert.ErrorIs(t, err, ErrPasswordResetRequired)
+	assert.Nil(t, result)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyMFA_UnverifiedBlocked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockMFA := new(MockMFAChallenger)
+	authService := NewAuthService(mockUserRepo, mockJWTService, new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), mockMFA, VerificationPolicyBlock, time.Hour)
+
+	user, _ := newTOTPUser(t, newTestSecretBox(t))
+
+	mockMFA.On("VerifyChallenge", mock.Anything, "mfa-token", "123456").Return(user, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+	// Act
+	result, err := authService.VerifyMFA(context.Background(), "mfa-token", "123456")
+
+	// Assert: политика block действует и на втором шаге входа
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+	assert.Nil(t, result)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMFAService_VerifyChallenge_WrongCodeKeepsToken(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockLockout := new(MockLoginLockout)
+	box := newTestSecretBox(t)
+	mfaService := NewMFAService(mockUserRepo, mockTokenRepo, mockLockout, box, testTokenSecret, 5*time.Minute)
+
+	user, _ := newTOTPUser(t, box)
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenMFAChallenge)
+	require.NoError(t, err)
+
+	stored := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenMFAChallenge,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	mockTokenRepo.On("GetUserToken", mock.Anything, models.UserTokenMFAChallenge, tokenHash).Return(stored, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil).Once()
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(true, nil).Once()
+
+	// Act & Assert: неверные коды учитываются в блокировке входа
+	_, err = mfaService.VerifyChallenge(context.Background(), token, "000000")
+	assert.ErrorIs(t, err, ErrInvalidTOTPCode)
+	_, err = mfaService.VerifyChallenge(context.Background(), token, "000000")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// Поддельный токен отклоняется без обращения к БД
+	_, err = mfaService.VerifyChallenge(context.Background(), "forged.token", "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+
+	mockTokenRepo.AssertNumberOfCalls(t, "GetUserToken", 2)
+	mockTokenRepo.AssertNotCalled(t, "ConsumeUserToken", mock.Anything, mock.Anything, mock.Anything)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestMFAService_VerifyChallenge_ConsumesToken(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockLockout := new(MockLoginLockout)
+	box := newTestSecretBox(t)
+	mfaService := NewMFAService(mockUserRepo, mockTokenRepo, mockLockout, box, testTokenSecret, 5*time.Minute)
+
+	user, secret := newTOTPUser(t, box)
+	user.FailedLoginAttempts = 3
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenMFAChallenge)
+	require.NoError(t, err)
+
+	stored := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenMFAChallenge,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	mockTokenRepo.On("GetUserToken", mock.Anything, models.UserTokenMFAChallenge, tokenHash).Return(stored, nil)
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenMFAChallenge, tokenHash).Return(stored, nil).Once()
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UseTOTPStep", mock.Anything, user.ID, mock.AnythingOfType("int64")).Return(true, nil).Once()
+	mockLockout.On("Reset", mock.Anything, user).Return(nil).Once()
+
+	// Act
+	result, err := mfaService.VerifyChallenge(context.Background(), token, currentTOTPCode(t, secret))
+
+	// Assert: верный код гасит токен и обнуляет счетчик неудачных попыток
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, result.ID)
+	mockTokenRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestMFAService_VerifyTOTP_RejectsReplayedCode(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockLockout := new(MockLoginLockout)
+	box := newTestSecretBox(t)
+	mfaService := NewMFAService(mockUserRepo, new(MockUserTokenRepository), mockLockout, box, testTokenSecret, 5*time.Minute)
+
+	user, secret := newTOTPUser(t, box)
+
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	// Код этого периода уже был принят
+	mockUserRepo.On("UseTOTPStep", mock.Anything, user.ID, mock.AnythingOfType("int64")).Return(false, nil)
+	mockLockout.On("RecordFailure", mock.Anything, user).Return(false, nil)
+
+	// Act
+	result, err := mfaService.VerifyTOTP(context.Background(), user.ID.String(), currentTOTPCode(t, secret))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidTOTPCode)
+	assert.Nil(t, result)
+	mockLockout.AssertExpectations(t)
+}
+
+func TestTOTPService_EnrollAndConfirm(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	box := newTestSecretBox(t)
+	totpService := NewTOTPService(mockUserRepo, new(MockTOTPVerifier), mockUsers, box, "Auth Service")
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("SetTOTPSecret", mock.Anything, user.ID, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { user.TOTPSecret = args.String(2) }).
+		Return(nil)
+
+	// Act: начинаем подключение
+	enrollment, err := totpService.EnrollTOTP(context.Background(), user.ID.String())
+
+	// Assert: в БД хранится только зашифрованный секрет
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.NotEmpty(t, enrollment.QRCode)
+	assert.NotContains(t, user.TOTPSecret, enrollment.Secret)
+
+	// Неверный код не включает TOTP
+	assert.ErrorIs(t, totpService.ConfirmTOTP(context.Background(), user.ID.String(), "000000"), ErrInvalidTOTPCode)
+
+	// Act: подтверждаем кодом из приложения
+	step := auth.TOTPStep(time.Now())
+	code, err := auth.TOTPCode(enrollment.Secret, step)
+	require.NoError(t, err)
+	mockUserRepo.On("EnableTOTP", mock.Anything, user.ID, user.TOTPSecret, step).Return(nil).Once()
+	mockUsers.On("InvalidateUser", user.Email).Return().Once()
+	err = totpService.ConfirmTOTP(context.Background(), user.ID.String(), code)
+
+	// Assert
+	require.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestOAuthService_Authorize_RequiresTOTPCode(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	mockMFA := new(MockTOTPVerifier)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), mockAuthenticator, mockMFA, new(MockJWTService), time.Minute)
+
+	user, _ := newTOTPUser(t, newTestSecretBox(t))
+	req := &models.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "spa",
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "profile",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: auth.PKCEMethodS256,
+	}
+
+	mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+	mockAuthenticator.On("Authenticate", mock.Anything, user.Email, "password123").Return(user, nil)
+	mockMFA.On("VerifyTOTP", mock.Anything, user.ID.String(), "000000").Return(nil, ErrInvalidTOTPCode)
+
+	// Act & Assert: без кода и с неверным кодом авторизационный код не выдается
+	_, _, err := oauthService.Authorize(context.Background(), req, user.Email, "password123", "")
+	assert.ErrorIs(t, err, ErrMFARequired)
+	_, _, err = oauthService.Authorize(context.Background(), req, user.Email, "password123", "000000")
+	assert.ErrorIs(t, err, ErrInvalidTOTPCode)
+
+	mockOAuthRepo.AssertNotCalled(t, "CreateAuthorizationCode", mock.Anything, mock.Anything)
+	mockMFA.AssertExpectations(t)
+}
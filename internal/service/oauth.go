@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// Коды ошибок OAuth 2.0 (RFC 6749, разделы 4.1.2.1 и 5.2)
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrServerError             = "server_error"
+)
+
+// Ошибки, при которых пользователя нельзя перенаправлять на redirect_uri
+var (
+	ErrUnknownClient      = errors.New("unknown client_id")
+	ErrInvalidRedirectURI = errors.New("redirect_uri is not registered for this client")
+)
+
+// OAuthError - ошибка протокола, которая возвращается клиенту в поле error
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthTokenResponse - ответ token endpoint (RFC 6749, раздел 5.1)
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OAuthService struct {
+	oauthRepo     OAuthRepository
+	userRepo      UserRepository
+	authenticator Authenticator
+	mfa           TOTPVerifier
+	jwtService    JWTService
+	codeTTL       time.Duration
+}
+
+func NewOAuthService(oauthRepo OAuthRepository, userRepo UserRepository, authenticator Authenticator, mfa TOTPVerifier, jwtService JWTService, codeTTL time.Duration) *OAuthService {
+	return &OAuthService{
+		oauthRepo:     oauthRepo,
+		userRepo:      userRepo,
+		authenticator: authenticator,
+		mfa:           mfa,
+		jwtService:    jwtService,
+		codeTTL:       codeTTL,
+	}
+}
+
+// ValidateAuthorizeRequest проверяет запрос авторизации и возвращает redirect_uri,
+// на который можно вернуть результат. Если redirect_uri пустой, ошибку нужно
+// показать пользователю, а не отправлять клиенту. Поле Scope запроса заменяется
+// на scopes, которые получит клиент.
+func (s *OAuthService) ValidateAuthorizeRequest(ctx context.Context, req *models.AuthorizeRequest) (string, error) {
+	client, err := s.oauthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrClientNotFound) {
+			return "", ErrUnknownClient
+		}
+		return "", err
+	}
+
+	redirectURI, err := resolveRedirectURI(client, req.RedirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	if req.ResponseType != "code" {
+		return redirectURI, newOAuthError(OAuthErrUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if req.CodeChallenge == "" {
+		return redirectURI, newOAuthError(OAuthErrInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != auth.PKCEMethodS256 {
+		return redirectURI, newOAuthError(OAuthErrInvalidRequest, "code_challenge_method must be S256")
+	}
+	if !auth.ValidCodeChallenge(req.CodeChallenge) {
+		return redirectURI, newOAuthError(OAuthErrInvalidRequest, "malformed code_challenge")
+	}
+
+	scopes, err := grantedUserScopes(client, req.Scope)
+	if err != nil {
+		return redirectURI, err
+	}
+	req.Scope = strings.Join(scopes, " ")
+
+	return redirectURI, nil
+}
+
+// Authorize проверяет учетные данные пользователя и выдает одноразовый код авторизации.
+// Если у пользователя включена TOTP, вместе с паролем нужен код из приложения.
+func (s *OAuthService) Authorize(ctx context.Context, req *models.AuthorizeRequest, login, password, totpCode string) (redirectURI, code string, err error) {
+	redirectURI, err = s.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return redirectURI, "", err
+	}
+
+	user, err := s.authenticator.Authenticate(ctx, login, password)
+	if err != nil {
+		return redirectURI, "", err
+	}
+	if user.TOTPEnabled() {
+		if totpCode == "" {
+			return redirectURI, "", ErrMFARequired
+		}
+		if user, err = s.mfa.VerifyTOTP(ctx, user.ID.String(), totpCode); err != nil {
+			return redirectURI, "", err
+		}
+	}
+
+	code, codeHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return redirectURI, "", errors.New("failed to generate authorization code")
+	}
+
+	now := time.Now()
+	err = s.oauthRepo.CreateAuthorizationCode(ctx, &models.AuthorizationCode{
+		CodeHash:            codeHash,
+		ClientID:            req.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         redirectURI,
+		RedirectURIProvided: req.RedirectURI != "",
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           now.Add(s.codeTTL),
+		CreatedAt:           now,
+	})
+	if err != nil {
+		return redirectURI, "", err
+	}
+
+	return redirectURI, code, nil
+}
+
+// Exchange обрабатывает запрос к token endpoint
+func (s *OAuthService) Exchange(ctx context.Context, req *models.TokenRequest) (*OAuthTokenResponse, error) {
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeAuthorizationCode(ctx, req)
+	case "refresh_token":
+		return s.exchangeRefreshToken(ctx, req)
+	case "client_credentials":
+		return s.exchangeClientCredentials(ctx, req)
+	case "":
+		return nil, newOAuthError(OAuthErrInvalidRequest, "grant_type is required")
+	default:
+		return nil, newOAuthError(OAuthErrUnsupportedGrantType, "unsupported grant_type")
+	}
+}
+
+// exchangeAuthorizationCode обменивает код авторизации и code_verifier на токены
+func (s *OAuthService) exchangeAuthorizationCode(ctx context.Context, req *models.TokenRequest) (*OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" || req.ClientID == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "code, code_verifier and client_id are required")
+	}
+
+	if _, err := s.AuthenticateClient(ctx, req.ClientID, req.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	// Код помечается использованным до остальных проверок: неудачная попытка тоже его сжигает
+	code, err := s.oauthRepo.ConsumeAuthorizationCode(ctx, auth.HashOpaqueToken(req.Code))
+	if err != nil {
+		if errors.Is(err, postgres.ErrAuthorizationCodeNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "invalid or already used authorization code")
+		}
+		return nil, err
+	}
+
+	switch {
+	case time.Now().After(code.ExpiresAt):
+		return nil, newOAuthError(OAuthErrInvalidGrant, "authorization code expired")
+	case code.ClientID != req.ClientID:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "authorization code was issued to another client")
+	// redirect_uri из запроса авторизации обязателен и при обмене (RFC 6749, раздел 4.1.3)
+	case (code.RedirectURIProvided || req.RedirectURI != "") && req.RedirectURI != code.RedirectURI:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "redirect_uri mismatch")
+	case !auth.VerifyPKCE(req.CodeVerifier, code.CodeChallenge, code.CodeChallengeMethod):
+		return nil, newOAuthError(OAuthErrInvalidGrant, "code_verifier does not match code_challenge")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, code.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "user no longer exists")
+		}
+		return nil, err
+	}
+	// Пока код ждал обмена, аккаунт могли удалить, приостановить или потребовать смену пароля
+	if user.IsDeleted() || user.IsSuspended() || user.PasswordResetRequired {
+		return nil, newOAuthError(OAuthErrInvalidGrant, "user account is not active")
+	}
+
+	authResponse, err := s.authenticator.IssueClientTokens(ctx, user, code.ClientID, strings.Fields(code.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return toOAuthTokenResponse(authResponse), nil
+}
+
+// exchangeRefreshToken обменивает refresh токен на новую пару с ротацией.
+// Клиент проходит аутентификацию и может обменять только выданный ему токен (RFC 6749, раздел 6).
+func (s *OAuthService) exchangeRefreshToken(ctx context.Context, req *models.TokenRequest) (*OAuthTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "refresh_token is required")
+	}
+
+	client, err := s.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	authResponse, err := s.authenticator.RefreshClient(ctx, client.ClientID, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, err.Error())
+		}
+		return nil, err
+	}
+
+	return toOAuthTokenResponse(authResponse), nil
+}
+
+// exchangeClientCredentials выдает сервисный токен конфиденциальному клиенту.
+// Токен содержит client_id и scopes вместо данных пользователя; refresh токен не выдается.
+func (s *OAuthService) exchangeClientCredentials(ctx context.Context, req *models.TokenRequest) (*OAuthTokenResponse, error) {
+	client, err := s.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.IsConfidential() {
+		return nil, newOAuthError(OAuthErrUnauthorizedClient, "public clients cannot use client_credentials")
+	}
+
+	scopes, err := grantedScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.jwtService.GenerateClientToken(client.ClientID, scopes)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.jwtService.Expiration().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// AuthenticateClient проверяет client_id и, для конфиденциальных клиентов, секрет
+func (s *OAuthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client authentication required")
+	}
+
+	client, err := s.oauthRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrClientNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if !client.IsConfidential() {
+		if clientSecret != "" {
+			return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" || checkPassword(client.SecretHash, clientSecret) != nil {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	return client, nil
+}
+
+// grantedScopes сверяет запрошенные scopes с разрешенными клиенту (client credentials
+// и токены пользователей, выданные через authorization code).
+// Если scope не передан, выдаются все разрешенные. Scope account клиенты не получают:
+// он выдается только при входе напрямую.
+func grantedScopes(client *models.OAuthClient, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return withoutScope(client.Scopes, auth.ScopeAccount), nil
+	}
+
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, newOAuthError(OAuthErrInvalidScope, "scope "+scope+" is not allowed for this client")
+		}
+	}
+	return withoutScope(scopes, auth.ScopeAccount), nil
+}
+
+// grantedUserScopes - grantedScopes для токенов пользователей, выданных через
+// authorization code. Scope admin клиент для пользователя не получает, даже если
+// он разрешен ему для client credentials.
+func grantedUserScopes(client *models.OAuthClient, requested string) ([]string, error) {
+	scopes, err := grantedScopes(client, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	return withoutScope(scopes, auth.ScopeAdmin), nil
+}
+
+// withoutScope возвращает копию scopes без указанного scope
+func withoutScope(scopes []string, scope string) []string {
+	return slices.DeleteFunc(slices.Clone(scopes), func(s string) bool {
+		return s == scope
+	})
+}
+
+// resolveRedirectURI сверяет redirect_uri с зарегистрированными (точное совпадение).
+// Если параметр не передан, а у клиента ровно один адрес, используется он.
+func resolveRedirectURI(client *models.OAuthClient, redirectURI string) (string, error) {
+	if redirectURI == "" {
+		if len(client.RedirectURIs) == 1 {
+			return client.RedirectURIs[0], nil
+		}
+		return "", ErrInvalidRedirectURI
+	}
+
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+	return redirectURI, nil
+}
+
+func toOAuthTokenResponse(authResponse *AuthResponse) *OAuthTokenResponse {
+	return &OAuthTokenResponse{
+		AccessToken:  authResponse.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(authResponse.TokenExpiresAt).Round(time.Second).Seconds()),
+		RefreshToken: authResponse.RefreshToken,
+		Scope:        authResponse.Scope,
+	}
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Пример из RFC 7636, приложение B
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// MockOAuthRepository - мок репозитория OAuth клиентов и кодов
+type MockOAuthRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthRepository) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthRepository) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (m *MockOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+	args := m.Called(ctx, codeHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthorizationCode), args.Error(1)
+}
+
+// MockAuthenticator - мок проверки учетных данных и выдачи токенов
+type MockAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	args := m.Called(ctx, email, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockAuthenticator) IssueClientTokens(ctx context.Context, user *models.User, clientID string, scopes []string) (*AuthResponse, error) {
+	args := m.Called(ctx, user, clientID, scopes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthenticator) CompleteLogin(ctx context.Context, user *models.User) (*AuthResponse, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthenticator) RefreshClient(ctx context.Context, clientID, refreshToken string) (*AuthResponse, error) {
+	args := m.Called(ctx, clientID, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuthResponse), args.Error(1)
+}
+
+var testClient = &models.OAuthClient{
+	ClientID:     "spa",
+	RedirectURIs: []string{"https://app.example.com/callback"},
+	Scopes:       []string{"profile"},
+}
+
+func TestOAuthService_ValidateAuthorizeRequest_RejectsUnregisteredRedirect(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), new(MockAuthenticator), new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+	mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+
+	// Act
+	redirectURI, err := oauthService.ValidateAuthorizeRequest(context.Background(), &models.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "spa",
+		RedirectURI:         "https://evil.example.com/callback",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: auth.PKCEMethodS256,
+	})
+
+	// Assert: на незарегистрированный адрес перенаправлять нельзя
+	assert.ErrorIs(t, err, ErrInvalidRedirectURI)
+	assert.Empty(t, redirectURI)
+}
+
+func TestOAuthService_Exchange_AuthorizationCodeWithPKCE(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	oauthService := NewOAuthService(mockOAuthRepo, mockUserRepo, mockAuthenticator, new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	code := &models.AuthorizationCode{
+		ClientID:            "spa",
+		UserID:              user.ID,
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "profile",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: auth.PKCEMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+
+	// Настраиваем моки
+	mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+	mockOAuthRepo.On("ConsumeAuthorizationCode", mock.Anything, auth.HashOpaqueToken("auth-code")).Return(code, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockAuthenticator.On("IssueClientTokens", mock.Anything, user, "spa", []string{"profile"}).Return(&AuthResponse{
+		User:           user,
+		Token:          "jwt-token",
+		TokenExpiresAt: time.Now().Add(15 * time.Minute),
+		RefreshToken:   "refresh-token",
+		Scope:          "profile",
+	}, nil)
+
+	// Act
+	result, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         "auth-code",
+		RedirectURI:  "https://app.example.com/callback",
+		ClientID:     "spa",
+		CodeVerifier: testCodeVerifier,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "jwt-token", result.AccessToken)
+	assert.Equal(t, "Bearer", result.TokenType)
+	assert.Equal(t, int64(900), result.ExpiresIn)
+	assert.Equal(t, "profile", result.Scope)
+
+	mockOAuthRepo.AssertExpectations(t)
+	mockAuthenticator.AssertExpectations(t)
+}
+
+func TestOAuthService_Exchange_WrongVerifier(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), mockAuthenticator, new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+	code := &models.AuthorizationCode{
+		ClientID:            "spa",
+		UserID:              uuid.New(),
+		RedirectURI:         "https://app.example.com/callback",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: auth.PKCEMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+
+	// Настраиваем моки
+	mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+	mockOAuthRepo.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(code, nil)
+
+	// Act
+	result, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         "auth-code",
+		ClientID:     "spa",
+		CodeVerifier: "wrong-verifier-wrong-verifier-wrong-verifier-0",
+	})
+
+	// Assert
+	var oauthErr *OAuthError
+	assert.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, OAuthErrInvalidGrant, oauthErr.Code)
+	assert.Nil(t, result)
+	mockAuthenticator.AssertNotCalled(t, "IssueClientTokens", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOAuthService_Exchange_RedirectURIMustMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		provided    bool
+		redirectURI string
+		wantErr     bool
+	}{
+		{"provided and repeated", true, "https://app.example.com/callback", false},
+		{"provided but omitted", true, "", true},
+		{"provided but different", true, "https://app.example.com/other", true},
+		{"defaulted and omitted", false, "", false},
+		{"defaulted but different", false, "https://app.example.com/other", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockOAuthRepo := new(MockOAuthRepository)
+			mockUserRepo := new(MockUserRepository)
+			mockAuthenticator := new(MockAuthenticator)
+			oauthService := NewOAuthService(mockOAuthRepo, mockUserRepo, mockAuthenticator, new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			code := &models.AuthorizationCode{
+				ClientID:            "spa",
+				UserID:              user.ID,
+				RedirectURI:         "https://app.example.com/callback",
+				RedirectURIProvided: tt.provided,
+				CodeChallenge:       testCodeChallenge,
+				CodeChallengeMethod: auth.PKCEMethodS256,
+				ExpiresAt:           time.Now().Add(time.Minute),
+			}
+			mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+			mockOAuthRepo.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(code, nil)
+			mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+			mockAuthenticator.On("IssueClientTokens", mock.Anything, user, "spa", mock.Anything).Return(&AuthResponse{User: user, Token: "jwt-token"}, nil)
+
+			// Act
+			_, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+				GrantType:    "authorization_code",
+				Code:         "auth-code",
+				RedirectURI:  tt.redirectURI,
+				ClientID:     "spa",
+				CodeVerifier: testCodeVerifier,
+			})
+
+			// Assert
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var oauthErr *OAuthError
+			assert.ErrorAs(t, err, &oauthErr)
+			assert.Equal(t, OAuthErrInvalidGrant, oauthErr.Code)
+			mockAuthenticator.AssertNotCalled(t, "IssueClientTokens", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOAuthService_Exchange_RejectsInactiveUser(t *testing.T) {
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name string
+		user *models.User
+	}{
+		{"suspended", &models.User{ID: uuid.New(), Status: models.UserStatusSuspended}},
+		{"deleted", &models.User{ID: uuid.New(), DeletedAt: &deletedAt}},
+		{"password reset required", &models.User{ID: uuid.New(), PasswordResetRequired: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockOAuthRepo := new(MockOAuthRepository)
+			mockUserRepo := new(MockUserRepository)
+			mockAuthenticator := new(MockAuthenticator)
+			oauthService := NewOAuthService(mockOAuthRepo, mockUserRepo, mockAuthenticator, new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+			code := &models.AuthorizationCode{
+				ClientID:            "spa",
+				UserID:              tt.user.ID,
+				RedirectURI:         "https://app.example.com/callback",
+				CodeChallenge:       testCodeChallenge,
+				CodeChallengeMethod: auth.PKCEMethodS256,
+				ExpiresAt:           time.Now().Add(time.Minute),
+			}
+			mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+			mockOAuthRepo.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(code, nil)
+			mockUserRepo.On("GetUserByID", mock.Anything, tt.user.ID.String()).Return(tt.user, nil)
+
+			// Act
+			result, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+				GrantType:    "authorization_code",
+				Code:         "auth-code",
+				ClientID:     "spa",
+				CodeVerifier: testCodeVerifier,
+			})
+
+			// Assert
+			var oauthErr *OAuthError
+			assert.ErrorAs(t, err, &oauthErr)
+			assert.Equal(t, OAuthErrInvalidGrant, oauthErr.Code)
+			assert.Nil(t, result)
+			mockAuthenticator.AssertNotCalled(t, "IssueClientTokens", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOAuthService_Exchange_ClientCredentials(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	mockJWTService := new(MockJWTService)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), new(MockAuthenticator), new(MockTOTPVerifier), mockJWTService, time.Minute)
+
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to generate secret hash: %v", err)
+	}
+	client := &models.OAuthClient{
+		ClientID:   "billing",
+		SecretHash: string(secretHash),
+		Scopes:     []string{"users:read", "users:write"},
+	}
+
+	// Настраиваем моки
+	mockOAuthRepo.On("GetClient", mock.Anything, "billing").Return(client, nil)
+	mockJWTService.On("GenerateClientToken", "billing", []string{"users:read"}).Return("service-token", nil)
+
+	// Act
+	result, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     "billing",
+		ClientSecret: "s3cret",
+		Scope:        "users:read",
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "service-token", result.AccessToken)
+	assert.Equal(t, "users:read", result.Scope)
+	assert.Empty(t, result.RefreshToken)
+
+	// Неверный секрет и недоступный scope отклоняются
+	_, err = oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     "billing",
+		ClientSecret: "wrong",
+	})
+	var oauthErr *OAuthError
+	assert.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, OAuthErrInvalidClient, oauthErr.Code)
+
+	_, err = oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     "billing",
+		ClientSecret: "s3cret",
+		Scope:        "admin",
+	})
+	assert.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, OAuthErrInvalidScope, oauthErr.Code)
+
+	mockJWTService.AssertExpectations(t)
+}
+
+func TestOAuthService_Exchange_RefreshTokenRequiresClientAuthentication(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	mockAuthenticator := new(MockAuthenticator)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), mockAuthenticator, new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+	mockOAuthRepo.On("GetClient", mock.Anything, "spa").Return(testClient, nil)
+	mockOAuthRepo.On("GetClient", mock.Anything, "unknown").Return(nil, postgres.ErrClientNotFound)
+	mockAuthenticator.On("RefreshClient", mock.Anything, "spa", "refresh-token").Return(&AuthResponse{
+		Token:        "jwt-token",
+		RefreshToken: "next-refresh-token",
+		Scope:        "profile",
+	}, nil)
+
+	// Act & Assert: без client_id и с неизвестным клиентом токен не обменивается
+	for _, clientID := range []string{"", "unknown"} {
+		_, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+			GrantType:    "refresh_token",
+			RefreshToken: "refresh-token",
+			ClientID:     clientID,
+		})
+		var oauthErr *OAuthError
+		require.ErrorAs(t, err, &oauthErr)
+		assert.Equal(t, OAuthErrInvalidClient, oauthErr.Code)
+	}
+
+	result, err := oauthService.Exchange(context.Background(), &models.TokenRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: "refresh-token",
+		ClientID:     "spa",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "next-refresh-token", result.RefreshToken)
+
+	mockAuthenticator.AssertNumberOfCalls(t, "RefreshClient", 1)
+}
+
+func TestOAuthService_ValidateAuthorizeRequest_NeverGrantsFirstPartyScopes(t *testing.T) {
+	// Arrange
+	mockOAuthRepo := new(MockOAuthRepository)
+	oauthService := NewOAuthService(mockOAuthRepo, new(MockUserRepository), new(MockAuthenticator), new(MockTOTPVerifier), new(MockJWTService), time.Minute)
+
+	// Клиенту разрешены admin и account
+	client := &models.OAuthClient{
+		ClientID:     "support-tool",
+		RedirectURIs: []string{"https://support.example.com/callback"},
+		Scopes:       []string{"profile", auth.ScopeAdmin, auth.ScopeAccount},
+	}
+	mockOAuthRepo.On("GetClient", mock.Anything, "support-tool").Return(client, nil)
+
+	for _, requested := range []string{"", "profile admin account"} {
+		req := &models.AuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            "support-tool",
+			Scope:               requested,
+			CodeChallenge:       testCodeChallenge,
+			CodeChallengeMethod: auth.PKCEMethodS256,
+		}
+
+		// Act
+		_, err := oauthService.ValidateAuthorizeRequest(context.Background(), req)
+
+		// Assert: пользовательский токен получит только profile
+		require.NoError(t, err)
+		assert.Equal(t, "profile", req.Scope)
+	}
+}
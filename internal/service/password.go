@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// ErrInvalidResetToken - токен сброса пароля поддельный, использованный или истекший
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+type PasswordService struct {
+	userRepo    UserRepository
+	refreshRepo RefreshTokenRepository
+	tokenRepo   UserTokenRepository
+	mailer      PasswordResetMailer
+	users       UserCacheInvalidator
+	secret      []byte
+	resetURL    string
+	resetTTL    time.Duration
+}
+
+func NewPasswordService(userRepo UserRepository, refreshRepo RefreshTokenRepository, tokenRepo UserTokenRepository, mailer PasswordResetMailer, users UserCacheInvalidator, secret, resetURL string, resetTTL time.Duration) *PasswordService {
+	return &PasswordService{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		tokenRepo:   tokenRepo,
+		mailer:      mailer,
+		users:       users,
+		secret:      []byte(secret),
+		resetURL:    resetURL,
+		resetTTL:    resetTTL,
+	}
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля.
+// Для неизвестного адреса ничего не делает и не возвращает ошибку,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *PasswordService) RequestPasswordReset(ctx context.Context, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	recentlySent, err := userTokenRecentlySent(ctx, s.tokenRepo, user.ID, models.UserTokenResetPassword)
+	if err != nil || recentlySent {
+		return err
+	}
+
+	return s.SendPasswordReset(ctx, user)
+}
+
+// SendPasswordReset выпускает токен сброса и отправляет ссылку на адрес пользователя
+func (s *PasswordService) SendPasswordReset(ctx context.Context, user *models.User) error {
+	token, err := issueUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenResetPassword, user.ID, user.Email, s.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendPasswordResetEmailAsync(user.Email, s.resetURL+"?token="+url.QueryEscape(token))
+	log.Printf("📨 Password reset email queued for: %s", user.Email)
+
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма и завершает
+// все сессии пользователя: access и refresh токены отзываются, кеш пользователя сбрасывается
+func (s *PasswordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// Пароль проверяется до использования токена, чтобы ошибка ввода не сжигала ссылку
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	stored, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenResetPassword, token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	// Письмо ушло на прежний адрес - после смены email ссылка не действует
+	if user.Email != stored.Email {
+		return ErrInvalidResetToken
+	}
+
+	// Смена пароля увеличивает поколение токенов - выданные access токены перестают приниматься
+	if _, err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	log.Printf("🔑 Password reset for user %s, sessions revoked", user.ID)
+	return nil
+}
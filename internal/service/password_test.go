@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockUserCacheInvalidator - мок сброса кеша пользователей
+type MockUserCacheInvalidator struct {
+	mock.Mock
+}
+
+func (m *MockUserCacheInvalidator) InvalidateUser(email string) {
+	m.Called(email)
+}
+
+func TestPasswordService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockMailer := new(MockMailer)
+	passwordService := NewPasswordService(mockUserRepo, new(MockRefreshTokenRepository), mockTokenRepo, mockMailer, new(MockUserCacheInvalidator), testTokenSecret, "https://app.example.com/reset", time.Hour)
+
+	mockUserRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return(nil, postgres.ErrUserNotFound)
+
+	// Act
+	err := passwordService.RequestPasswordReset(context.Background(), "unknown@example.com")
+
+	// Assert: ответ такой же, как для зарегистрированного адреса
+	assert.NoError(t, err)
+	mockTokenRepo.AssertNotCalled(t, "CreateUserToken", mock.Anything, mock.Anything)
+	mockMailer.AssertNotCalled(t, "SendPasswordResetEmailAsync", mock.Anything, mock.Anything)
+}
+
+func TestPasswordService_RequestPasswordReset_SendsLink(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockMailer := new(MockMailer)
+	passwordService := NewPasswordService(mockUserRepo, new(MockRefreshTokenRepository), mockTokenRepo, mockMailer, new(MockUserCacheInvalidator), testTokenSecret, "https://app.example.com/reset", time.Hour)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockTokenRepo.On("LastUserTokenCreatedAt", mock.Anything, user.ID, models.UserTokenResetPassword).Return(nil, nil)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.UserID == user.ID && token.Purpose == models.UserTokenResetPassword
+	})).Return(nil)
+	mockMailer.On("SendPasswordResetEmailAsync", user.Email, mock.AnythingOfType("string")).Return()
+
+	// Act
+	err := passwordService.RequestPasswordReset(context.Background(), user.Email)
+
+	// Assert
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockCache := new(MockUserCacheInvalidator)
+	passwordService := NewPasswordService(mockUserRepo, mockRefreshRepo, mockTokenRepo, new(MockMailer), mockCache, testTokenSecret, "https://app.example.com/reset", time.Hour)
+
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenResetPassword)
+	require.NoError(t, err)
+
+	// Настраиваем моки
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenResetPassword, tokenHash).Return(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenResetPassword,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdatePassword", mock.Anything, user.ID, mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+	})).Return(1, nil)
+	mockCache.On("InvalidateUser", user.Email).Return()
+	mockRefreshRepo.On("RevokeUserRefreshTokens", mock.Anything, user.ID).Return(nil)
+
+	// Act
+	err = passwordService.ResetPassword(context.Background(), token, "new-password")
+
+	// Assert: пароль обновлен, кеш сброшен, сессии отозваны
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestPasswordService_ResetPassword_InvalidToken(t *testing.T) {
+	// Arrange
+	mockTokenRepo := new(MockUserTokenRepository)
+	passwordService := NewPasswordService(new(MockUserRepository), new(MockRefreshTokenRepository), mockTokenRepo, new(MockMailer), new(MockUserCacheInvalidator), testTokenSecret, "https://app.example.com/reset", time.Hour)
+
+	// Токен подтверждения email не подходит для сброса пароля
+	token, _, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenVerifyEmail)
+	require.NoError(t, err)
+
+	// Act
+	err = passwordService.ResetPassword(context.Background(), token, "new-password")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	mockTokenRepo.AssertNotCalled(t, "ConsumeUserToken", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	// Часовые пояса проверяются по встроенной базе, а не по файлам системы
+	_ "time/tzdata"
+	"unicode"
+	"unicode/utf8"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// Ограничения полей профиля
+const (
+	maxDisplayNameLength    = 100
+	maxAvatarURLLength      = 2048
+	maxProfileMetadataBytes = 16 << 10
+)
+
+// localePattern - языковой тег BCP 47 вида ru, en-US, zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,4}$`)
+
+// ErrInvalidProfilePatch - тело PATCH не является JSON объектом
+var ErrInvalidProfilePatch = errors.New("profile patch must be a JSON object")
+
+// ProfileFieldError - недопустимое значение поля профиля
+type ProfileFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ProfileFieldError) Error() string {
+	return fmt.Sprintf("invalid profile field %s: %s", e.Field, e.Reason)
+}
+
+type ProfileService struct {
+	userRepo UserRepository
+	users    UserCacheInvalidator
+}
+
+func NewProfileService(userRepo UserRepository, users UserCacheInvalidator) *ProfileService {
+	return &ProfileService{
+		userRepo: userRepo,
+		users:    users,
+	}
+}
+
+// UpdateProfile применяет к профилю JSON merge patch (RFC 7396): отсутствующие поля
+// не меняются, null очищает поле, объекты (metadata) объединяются рекурсивно
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, patch []byte) (*models.User, error) {
+	var patchDoc map[string]any
+	if err := json.Unmarshal(patch, &patchDoc); err != nil || patchDoc == nil {
+		return nil, ErrInvalidProfilePatch
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := applyProfilePatch(&user.Profile, patchDoc)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.userRepo.UpdateProfile(ctx, user.ID, profile)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUsernameTaken) {
+			return nil, &ProfileFieldError{Field: "username", Reason: "is already taken"}
+		}
+		return nil, err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	log.Printf("👤 Profile updated for user %s", user.ID)
+	return updated, nil
+}
+
+// applyProfilePatch возвращает профиль с примененным merge patch.
+// Поля вне профиля (email, id и т.п.) изменить нельзя.
+func applyProfilePatch(current *models.Profile, patchDoc map[string]any) (*models.Profile, error) {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	for field := range patchDoc {
+		if _, ok := doc[field]; !ok {
+			return nil, &ProfileFieldError{Field: field, Reason: "field cannot be changed"}
+		}
+	}
+
+	data, err = json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return nil, err
+	}
+
+	var profile models.Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ProfileFieldError{Field: typeErr.Field, Reason: "unexpected " + typeErr.Value}
+		}
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// mergePatch объединяет документ с патчем по правилам RFC 7396
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+// validateProfile проверяет и нормализует поля профиля
+func validateProfile(profile *models.Profile) error {
+	if profile.Username != nil {
+		if err := ValidateUsername(*profile.Username); err != nil {
+			return &ProfileFieldError{Field: "username", Reason: err.Error()}
+		}
+	}
+
+	if profile.DisplayName != nil {
+		name := strings.TrimSpace(*profile.DisplayName)
+		switch {
+		case name == "":
+			return &ProfileFieldError{Field: "display_name", Reason: "must not be empty"}
+		case utf8.RuneCountInString(name) > maxDisplayNameLength:
+			return &ProfileFieldError{Field: "display_name", Reason: fmt.Sprintf("must be at most %d characters", maxDisplayNameLength)}
+		case strings.IndexFunc(name, unicode.IsControl) >= 0:
+			return &ProfileFieldError{Field: "display_name", Reason: "must not contain control characters"}
+		}
+		profile.DisplayName = &name
+	}
+
+	if profile.AvatarURL != nil {
+		avatar, err := url.Parse(*profile.AvatarURL)
+		if err != nil || len(*profile.AvatarURL) > maxAvatarURLLength || (avatar.Scheme != "https" && avatar.Scheme != "http") || avatar.Host == "" {
+			return &ProfileFieldError{Field: "avatar_url", Reason: "must be an absolute http(s) URL"}
+		}
+	}
+
+	if profile.Locale != nil && (len(*profile.Locale) > 35 || !localePattern.MatchString(*profile.Locale)) {
+		return &ProfileFieldError{Field: "locale", Reason: "must be a BCP 47 language tag"}
+	}
+
+	if profile.Timezone != nil {
+		if _, err := time.LoadLocation(*profile.Timezone); err != nil || *profile.Timezone == "" || *profile.Timezone == "Local" {
+			return &ProfileFieldError{Field: "timezone", Reason: "must be an IANA time zone name"}
+		}
+	}
+
+	if profile.Metadata != nil {
+		data, err := json.Marshal(profile.Metadata)
+		if err != nil || len(data) > maxProfileMetadataBytes {
+			return &ProfileFieldError{Field: "metadata", Reason: fmt.Sprintf("must be at most %d bytes", maxProfileMetadataBytes)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileService_UpdateProfile_MergePatch(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockUsers := new(MockUserCacheInvalidator)
+	profileService := NewProfileService(mockUserRepo, mockUsers)
+
+	displayName := "Old Name"
+	locale := "ru"
+	user := &models.User{
+		ID:    uuid.New(),
+		Email: "test@example.com",
+		Profile: models.Profile{
+			DisplayName: &displayName,
+			Locale:      &locale,
+			Metadata:    map[string]any{"theme": "dark", "beta": true},
+		},
+	}
+
+	var saved *models.Profile
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockUserRepo.On("UpdateProfile", mock.Anything, user.ID, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(2).(*models.Profile)
+	}).Return(user, nil)
+	mockUsers.On("InvalidateUser", user.Email).Return()
+
+	// Act
+	_, err := profileService.UpdateProfile(context.Background(), user.ID.String(),
+		[]byte(`{"display_name": "  New Name ", "locale": null, "timezone": "Europe/Moscow", "metadata": {"beta": null, "lang": "go"}}`))
+
+	// Assert: поле задано, null очистил поле, metadata объединена, отсутствующие поля не тронуты
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, "New Name", *saved.DisplayName)
+	assert.Nil(t, saved.Locale)
+	assert.Equal(t, "Europe/Moscow", *saved.Timezone)
+	assert.Nil(t, saved.AvatarURL)
+	assert.Equal(t, map[string]any{"theme": "dark", "lang": "go"}, saved.Metadata)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestProfileService_UpdateProfile_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		field string
+	}{
+		{name: "email is not editable", patch: `{"email": "other@example.com"}`, field: "email"},
+		{name: "reserved username", patch: `{"username": "root"}`, field: "username"},
+		{name: "empty display name", patch: `{"display_name": "   "}`, field: "display_name"},
+		{name: "relative avatar url", patch: `{"avatar_url": "/avatar.png"}`, field: "avatar_url"},
+		{name: "invalid locale", patch: `{"locale": "not a locale"}`, field: "locale"},
+		{name: "unknown timezone", patch: `{"timezone": "Mars/Olympus"}`, field: "timezone"},
+		{name: "metadata is not an object", patch: `{"metadata": "text"}`, field: "metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockUserRepo := new(MockUserRepository)
+			profileService := NewProfileService(mockUserRepo, new(MockUserCacheInvalidator))
+
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+			// Act
+			_, err := profileService.UpdateProfile(context.Background(), user.ID.String(), []byte(tt.patch))
+
+			// Assert
+			var fieldErr *ProfileFieldError
+			require.ErrorAs(t, err, &fieldErr)
+			assert.Equal(t, tt.field, fieldErr.Field)
+			mockUserRepo.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProfileService_UpdateProfile_NotAnObject(t *testing.T) {
+	profileService := NewProfileService(new(MockUserRepository), new(MockUserCacheInvalidator))
+
+	_, err := profileService.UpdateProfile(context.Background(), uuid.NewString(), []byte(`["display_name"]`))
+
+	assert.ErrorIs(t, err, ErrInvalidProfilePatch)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"auth-service/internal/models"
+)
+
+// ErrPasswordReauthRequired - у аккаунта есть пароль: действия подтверждаются им, а не кодом из письма
+var ErrPasswordReauthRequired = errors.New("account has a password, confirm the action with it")
+
+// reauthenticate повторно подтверждает личность вошедшего пользователя перед чувствительным
+// действием: паролем с учетом блокировки входа, а в аккаунте без пароля - кодом из письма
+// (EmailOTPReauth). Неверный пароль дает mismatch, неверный код - ErrInvalidOTP.
+func reauthenticate(ctx context.Context, lockout LoginLockout, users UserCacheInvalidator, codes EmailCodeVerifier, user *models.User, password, code string, mismatch error) error {
+	if user.HasPassword() {
+		return checkPasswordWithLockout(ctx, lockout, users, user, password, mismatch)
+	}
+
+	// Пустой код не тратит попытки проверки отправленного
+	if code == "" {
+		return ErrInvalidOTP
+	}
+	return codes.VerifyCode(ctx, user, models.EmailOTPReauth, code)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/cache"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// RevocationService хранит denylist отозванных access токенов в Postgres. В памяти
+// запоминаются только уже отозванные jti: повторно предъявленный отозванный токен
+// отклоняется без запроса к БД, любой другой проверяется одним запросом.
+// Токены пользователя также отзываются целиком увеличением поколения (смена пароля)
+// и перестают приниматься, пока аккаунт приостановлен или удален.
+type RevocationService struct {
+	repo   RevokedTokenRepository
+	states TokenStateRepository
+	cache  *cache.RevokedTokenCache
+}
+
+func NewRevocationService(repo RevokedTokenRepository, states TokenStateRepository) *RevocationService {
+	return &RevocationService{
+		repo:   repo,
+		states: states,
+		cache:  cache.NewRevokedTokenCache(),
+	}
+}
+
+// Revoke отзывает токен до момента его истечения
+func (s *RevocationService) Revoke(ctx context.Context, claims *auth.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	expiresAt := claims.ExpiresAt.Time
+	if err := s.repo.RevokeToken(ctx, claims.ID, claims.UserID, expiresAt); err != nil {
+		return err
+	}
+
+	s.cache.Add(claims.ID, expiresAt)
+	return nil
+}
+
+// IsRevoked проверяет, был ли токен отозван. Для токена пользователя denylist, поколение
+// и статус аккаунта читаются одним запросом, для токена сервиса - только denylist.
+func (s *RevocationService) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if claims.ID != "" && s.cache.Contains(claims.ID) {
+		return true, nil
+	}
+
+	if claims.Principal() == auth.PrincipalUser {
+		return s.isUserTokenRevoked(ctx, claims)
+	}
+
+	// Токены без jti выданы до появления denylist и отозваны быть не могут
+	if claims.ID == "" {
+		return false, nil
+	}
+
+	revoked, err := s.repo.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		s.remember(claims)
+	}
+
+	return revoked, nil
+}
+
+// isUserTokenRevoked проверяет, отозван ли токен пользователя, выдан ли он до последней
+// смены пароля и может ли аккаунт сейчас пользоваться токенами. Токены удаленных
+// пользователей тоже считаются отозванными.
+func (s *RevocationService) isUserTokenRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	generation, status, revoked, err := s.states.GetTokenState(ctx, claims.UserID, claims.ID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	if revoked {
+		s.remember(claims)
+		return true, nil
+	}
+	if status == models.UserStatusSuspended || status == models.UserStatusDeleted {
+		return true, nil
+	}
+	return claims.Generation < generation, nil
+}
+
+// remember запоминает отзыв токена локально: он мог произойти на другом инстансе
+func (s *RevocationService) remember(claims *auth.Claims) {
+	if claims.ExpiresAt != nil {
+		s.cache.Add(claims.ID, claims.ExpiresAt.Time)
+	}
+}
+
+// Prune удаляет записи о токенах, которые уже истекли бы сами
+func (s *RevocationService) Prune(ctx context.Context) error {
+	now := time.Now()
+	s.cache.Prune(now)
+
+	deleted, err := s.repo.DeleteExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("🧹 Pruned %d expired revoked tokens", deleted)
+	}
+	return nil
+}
+
+// StartPruning периодически чистит denylist, пока не отменен ctx
+func (s *RevocationService) StartPruning(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Prune(ctx); err != nil {
+				log.Printf("❌ Failed to prune revoked tokens: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRevokedTokenRepository - мок denylist в БД
+type MockRevokedTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRevokedTokenRepository) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevokedTokenRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRevokedTokenRepository) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockTokenStateRepository - мок чтения поколения токенов и статуса пользователя
+type MockTokenStateRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenStateRepository) GetTokenState(ctx context.Context, userID, jti string) (int, models.UserStatus, bool, error) {
+	args := m.Called(ctx, userID, jti)
+	return args.Int(0), args.Get(1).(models.UserStatus), args.Bool(2), args.Error(3)
+}
+
+func TestRevocationService_IsRevoked_TokenGeneration(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRevokedTokenRepository)
+	mockStates := new(MockTokenStateRepository)
+	revocationService := NewRevocationService(mockRepo, mockStates)
+
+	mockStates.On("GetTokenState", mock.Anything, "user-1", mock.Anything).Return(2, models.UserStatusActive, false, nil)
+	mockStates.On("GetTokenState", mock.Anything, "suspended-user", mock.Anything).Return(2, models.UserStatusSuspended, false, nil)
+	mockStates.On("GetTokenState", mock.Anything, "deleted-user", mock.Anything).Return(0, models.UserStatus(""), false, postgres.ErrUserNotFound)
+
+	tests := []struct {
+		name    string
+		claims  *auth.Claims
+		revoked bool
+	}{
+		{"issued before password change", &auth.Claims{UserID: "user-1", Generation: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "jti-old"}}, true},
+		{"current generation", &auth.Claims{UserID: "user-1", Generation: 2, RegisteredClaims: jwt.RegisteredClaims{ID: "jti-current"}}, false},
+		{"suspended user", &auth.Claims{UserID: "suspended-user", Generation: 2, RegisteredClaims: jwt.RegisteredClaims{ID: "jti-suspended"}}, true},
+		{"deleted user", &auth.Claims{UserID: "deleted-user", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-deleted"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			revoked, err := revocationService.IsRevoked(context.Background(), tt.claims)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.revoked, revoked)
+		})
+	}
+
+	// Denylist проверяется тем же запросом, отдельного обращения к нему нет
+	mockRepo.AssertNotCalled(t, "IsTokenRevoked", mock.Anything, mock.Anything)
+}
+
+func TestRevocationService_IsRevoked_RemembersRevokedToken(t *testing.T) {
+	// Arrange
+	mockStates := new(MockTokenStateRepository)
+	revocationService := NewRevocationService(new(MockRevokedTokenRepository), mockStates)
+
+	claims := &auth.Claims{UserID: "user-1", RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "jti-revoked",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	mockStates.On("GetTokenState", mock.Anything, "user-1", "jti-revoked").Return(0, models.UserStatusActive, true, nil).Once()
+
+	// Act: отзыв на другом инстансе виден из БД, повторная проверка обходится без запроса
+	first, err := revocationService.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	second, err := revocationService.IsRevoked(context.Background(), claims)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, first)
+	assert.True(t, second)
+	mockStates.AssertNumberOfCalls(t, "GetTokenState", 1)
+}
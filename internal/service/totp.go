@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/repository/postgres"
+)
+
+// Ошибки подключения TOTP
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication setup has not been started")
+)
+
+// totpQRCodeSize - размер PNG с QR-кодом в пикселях
+const totpQRCodeSize = 256
+
+// TOTPEnrollment - данные для добавления аккаунта в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte // PNG
+}
+
+// TOTPService подключает и отключает двухфакторную аутентификацию по TOTP (RFC 6238).
+// Секрет хранится зашифрованным; TOTP включается только после того, как пользователь
+// подтвердит первый код из приложения.
+type TOTPService struct {
+	userRepo UserRepository
+	mfa      TOTPVerifier
+	users    UserCacheInvalidator
+	box      *auth.SecretBox
+	issuer   string
+}
+
+func NewTOTPService(userRepo UserRepository, mfa TOTPVerifier, users UserCacheInvalidator, box *auth.SecretBox, issuer string) *TOTPService {
+	return &TOTPService{
+		userRepo: userRepo,
+		mfa:      mfa,
+		users:    users,
+		box:      box,
+		issuer:   issuer,
+	}
+}
+
+// EnrollTOTP генерирует новый секрет и возвращает его вместе с otpauth:// URI и QR-кодом.
+// До подтверждения вход не меняется; повторный вызов заменяет секрет.
+func (s *TOTPService) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate TOTP secret")
+	}
+	sealed, err := s.box.Seal(secret, user.ID.String())
+	if err != nil {
+		return nil, errors.New("failed to encrypt TOTP secret")
+	}
+
+	if err := s.userRepo.SetTOTPSecret(ctx, user.ID, sealed); err != nil {
+		if errors.Is(err, postgres.ErrTOTPStateChanged) {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	uri := auth.TOTPURI(s.issuer, user.Email, secret)
+	qrCode, err := auth.TOTPQRCode(uri, totpQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📱 TOTP enrollment started for user %s", user.ID)
+	return &TOTPEnrollment{Secret: secret, URI: uri, QRCode: qrCode}, nil
+}
+
+// ConfirmTOTP включает TOTP, если код из приложения подходит к секрету из EnrollTOTP
+func (s *TOTPService) ConfirmTOTP(ctx context.Context, userID, code string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled() {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	secret, err := s.box.Open(user.TOTPSecret, user.ID.String())
+	if err != nil {
+		return err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+
+	// Первый код сразу считается использованным
+	if err := s.userRepo.EnableTOTP(ctx, user.ID, user.TOTPSecret, step); err != nil {
+		if errors.Is(err, postgres.ErrTOTPStateChanged) {
+			return ErrTOTPNotEnrolled
+		}
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	log.Printf("🛡️ TOTP enabled for user %s", user.ID)
+	return nil
+}
+
+// DisableTOTP отключает TOTP после проверки текущего кода из приложения
+func (s *TOTPService) DisableTOTP(ctx context.Context, userID, code string) error {
+	user, err := s.mfa.VerifyTOTP(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+	s.users.InvalidateUser(user.Email)
+
+	log.Printf("🛡️ TOTP disabled for user %s", user.ID)
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+)
+
+// userTokenResendCooldown - как часто можно отправлять письмо с токеном одному пользователю
+const userTokenResendCooldown = time.Minute
+
+// errInvalidUserToken - токен из письма поддельный, использованный или истекший
+var errInvalidUserToken = errors.New("invalid user token")
+
+// ErrTooManyEmailRequests - на адрес недавно запрошено слишком много писем
+var ErrTooManyEmailRequests = errors.New("too many emails requested for this address, try again later")
+
+// EmailRateLimit - сколько писем одного назначения можно запросить на адрес за Window
+type EmailRateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// issueUserToken выдает подписанный одноразовый токен для письма на указанный адрес.
+// Предыдущие неиспользованные токены того же назначения перестают действовать.
+func issueUserToken(ctx context.Context, repo UserTokenRepository, secret []byte, purpose string, userID uuid.UUID, email string, ttl time.Duration) (string, error) {
+	return issueBoundUserToken(ctx, repo, secret, purpose, userID, email, "", ttl)
+}
+
+// issueBoundUserToken выдает токен, который примет только устройство с секретом,
+// хеш которого передан в bindingHash
+func issueBoundUserToken(ctx context.Context, repo UserTokenRepository, secret []byte, purpose string, userID uuid.UUID, email, bindingHash string, ttl time.Duration) (string, error) {
+	token, _, err := createUserToken(ctx, repo, secret, purpose, userID, email, bindingHash, ttl)
+	return token, err
+}
+
+// createUserToken выдает токен и возвращает также ID его записи,
+// чтобы привязать к ней данные, которые удаляются вместе с токеном
+func createUserToken(ctx context.Context, repo UserTokenRepository, secret []byte, purpose string, userID uuid.UUID, email, bindingHash string, ttl time.Duration) (string, uuid.UUID, error) {
+	token, tokenHash, err := auth.NewSignedToken(secret, purpose)
+	if err != nil {
+		return "", uuid.Nil, errors.New("failed to generate token")
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	err = repo.CreateUserToken(ctx, &models.UserToken{
+		ID:          id,
+		UserID:      userID,
+		Purpose:     purpose,
+		TokenHash:   tokenHash,
+		BindingHash: bindingHash,
+		Email:       email,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+
+	return token, id, nil
+}
+
+// consumeUserToken проверяет подпись и срок токена и помечает его использованным.
+// Для любого недействительного токена возвращает errInvalidUserToken.
+func consumeUserToken(ctx context.Context, repo UserTokenRepository, secret []byte, purpose, token string) (*models.UserToken, error) {
+	tokenHash, err := auth.VerifySignedToken(secret, purpose, token)
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	stored, err := repo.ConsumeUserToken(ctx, purpose, tokenHash)
+	return checkConsumedUserToken(stored, err)
+}
+
+// consumeBoundUserToken - consumeUserToken для токена, привязанного к устройству
+func consumeBoundUserToken(ctx context.Context, repo UserTokenRepository, secret []byte, purpose, token, bindingHash string) (*models.UserToken, error) {
+	tokenHash, err := auth.VerifySignedToken(secret, purpose, token)
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	stored, err := repo.ConsumeBoundUserToken(ctx, purpose, tokenHash, bindingHash)
+	return checkConsumedUserToken(stored, err)
+}
+
+// checkConsumedUserToken переводит результат использования токена в errInvalidUserToken
+func checkConsumedUserToken(stored *models.UserToken, err error) (*models.UserToken, error) {
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserTokenNotFound) {
+			return nil, errInvalidUserToken
+		}
+		return nil, err
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+
+	return stored, nil
+}
+
+// allowEmailRequest учитывает запрос письма на адрес или возвращает ErrTooManyEmailRequests.
+// Лимит действует и для незарегистрированных адресов.
+func allowEmailRequest(ctx context.Context, repo EmailRequestRepository, limit EmailRateLimit, email, purpose string) error {
+	allowed, err := repo.AllowEmailRequest(ctx, email, purpose, limit.Limit, time.Now().Add(-limit.Window))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyEmailRequests
+	}
+	return nil
+}
+
+// userTokenRecentlySent проверяет, отправлялось ли письмо этого назначения недавно
+func userTokenRecentlySent(ctx context.Context, repo UserTokenRepository, userID uuid.UUID, purpose string) (bool, error) {
+	lastSentAt, err := repo.LastUserTokenCreatedAt(ctx, userID, purpose)
+	if err != nil {
+		return false, err
+	}
+	return lastSentAt != nil && time.Since(*lastSentAt) < userTokenResendCooldown, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Ошибки username
+var (
+	ErrInvalidUsername  = errors.New("username must be 3-32 characters long and contain only letters, digits, '.', '_' or '-', starting with a letter or digit")
+	ErrUsernameReserved = errors.New("username is reserved")
+	ErrUsernameTaken    = errors.New("username is already taken")
+)
+
+// usernamePattern - латинские буквы, цифры, '.', '_' и '-'. Символ @ запрещен,
+// поэтому логин при входе однозначно отличается от email.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,31}$`)
+
+// reservedUsernames нельзя занять: они совпадают с путями сервиса или
+// могут выдать пользователя за администрацию
+var reservedUsernames = map[string]struct{}{
+	"abuse":         {},
+	"account":       {},
+	"admin":         {},
+	"administrator": {},
+	"api":           {},
+	"auth":          {},
+	"help":          {},
+	"hostmaster":    {},
+	"info":          {},
+	"login":         {},
+	"logout":        {},
+	"me":            {},
+	"moderator":     {},
+	"no-reply":      {},
+	"noreply":       {},
+	"null":          {},
+	"oauth":         {},
+	"postmaster":    {},
+	"profile":       {},
+	"register":      {},
+	"root":          {},
+	"security":      {},
+	"settings":      {},
+	"staff":         {},
+	"support":       {},
+	"system":        {},
+	"undefined":     {},
+	"webmaster":     {},
+}
+
+// ValidateUsername проверяет символы username и блоклист зарезервированных имен
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	if _, reserved := reservedUsernames[strings.ToLower(username)]; reserved {
+		return ErrUsernameReserved
+	}
+	return nil
+}
+
+// isEmailLogin сообщает, что при входе передан email, а не username
+func isEmailLogin(login string) bool {
+	return strings.Contains(login, "@")
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+)
+
+// VerificationPolicy определяет, что разрешено пользователю с неподтвержденным email
+type VerificationPolicy string
+
+const (
+	// VerificationPolicyOff - адрес не проверяется, письма с подтверждением не отправляются
+	VerificationPolicyOff VerificationPolicy = "off"
+	// VerificationPolicyRestrict - вход разрешен, но токены выдаются без scopes
+	VerificationPolicyRestrict VerificationPolicy = "restrict"
+	// VerificationPolicyBlock - вход запрещен до подтверждения адреса
+	VerificationPolicyBlock VerificationPolicy = "block"
+)
+
+// ParseVerificationPolicy разбирает значение политики из конфигурации
+func ParseVerificationPolicy(value string) (VerificationPolicy, error) {
+	switch policy := VerificationPolicy(value); policy {
+	case VerificationPolicyOff, VerificationPolicyRestrict, VerificationPolicyBlock:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown email verification policy %q", value)
+	}
+}
+
+// Ошибки подтверждения email
+var (
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+)
+
+type EmailVerificationService struct {
+	userRepo  UserRepository
+	tokenRepo UserTokenRepository
+	mailer    VerificationMailer
+	secret    []byte
+	baseURL   string
+	tokenTTL  time.Duration
+}
+
+func NewEmailVerificationService(userRepo UserRepository, tokenRepo UserTokenRepository, mailer VerificationMailer, secret, baseURL string, tokenTTL time.Duration) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    mailer,
+		secret:    []byte(secret),
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		tokenTTL:  tokenTTL,
+	}
+}
+
+// SendVerification выдает новый токен подтверждения и отправляет ссылку на адрес пользователя
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	token, err := issueUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenVerifyEmail, user.ID, user.Email, s.tokenTTL)
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendVerificationEmailAsync(user.Email, s.baseURL+"/auth/verify-email?token="+url.QueryEscape(token))
+	log.Printf("📨 Verification email queued for: %s", user.Email)
+
+	return nil
+}
+
+// ResendVerification повторно отправляет письмо с подтверждением.
+// Для неизвестных и уже подтвержденных адресов ничего не делает и не возвращает
+// ошибку, чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *EmailVerificationService) ResendVerification(ctx context.Context, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	recentlySent, err := userTokenRecentlySent(ctx, s.tokenRepo, user.ID, models.UserTokenVerifyEmail)
+	if err != nil || recentlySent {
+		return err
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail подтверждает адрес по токену из письма. Токен одноразовый
+// и действует, только пока адрес пользователя совпадает с адресом письма.
+func (s *EmailVerificationService) VerifyEmail(ctx context.Context, token string) error {
+	stored, err := consumeUserToken(ctx, s.tokenRepo, s.secret, models.UserTokenVerifyEmail, token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, stored.UserID, stored.Email); err != nil {
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	log.Printf("✅ Email verified for user %s", stored.UserID)
+	return nil
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/auth"
+	"auth-service/internal/models"
+	"auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockEmailVerifier - мок отправки ссылки для подтверждения адреса
+type MockEmailVerifier struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerifier) SendVerification(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+// MockUserTokenRepository - мок репозитория одноразовых токенов
+type MockUserTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserTokenRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockUserTokenRepository) GetUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *MockUserTokenRepository) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *MockUserTokenRepository) ConsumeBoundUserToken(ctx context.Context, purpose, tokenHash, bindingHash string) (*models.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash, bindingHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *MockUserTokenRepository) LastUserTokenCreatedAt(ctx context.Context, userID uuid.UUID, purpose string) (*time.Time, error) {
+	args := m.Called(ctx, userID, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockUserTokenRepository) DeleteUserTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+func (m *MockUserTokenRepository) ListUserTokens(ctx context.Context, userID uuid.UUID) ([]models.UserToken, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserToken), args.Error(1)
+}
+
+// MockMailer - мок отправки писем
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) SendVerificationEmailAsync(email, link string) {
+	m.Called(email, link)
+}
+
+func (m *MockMailer) SendPasswordResetEmailAsync(email, link string) {
+	m.Called(email, link)
+}
+
+func (m *MockMailer) SendEmailChangeConfirmationAsync(email, link string) {
+	m.Called(email, link)
+}
+
+func (m *MockMailer) SendEmailChangeNoticeAsync(email, newEmail, undoLink string) {
+	m.Called(email, newEmail, undoLink)
+}
+
+func (m *MockMailer) SendDataExportEmail(email, link string) error {
+	args := m.Called(email, link)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendAccountLockedEmailAsync(email string, lockedUntil time.Time) {
+	m.Called(email, lockedUntil)
+}
+
+func (m *MockMailer) SendMagicLinkEmailAsync(email, link string) {
+	m.Called(email, link)
+}
+
+func (m *MockMailer) SendOTPEmailAsync(email, code string, ttl time.Duration) {
+	m.Called(email, code, ttl)
+}
+
+const testTokenSecret = "token-secret"
+
+// newUnverifiedUser создает пользователя с неподтвержденным адресом и паролем password123
+func newUnverifiedUser(t *testing.T) *models.User {
+	t.Helper()
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return &models.User{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(passwordHash),
+	}
+}
+
+func TestAuthService_Login_UnverifiedBlocked(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	authService := NewAuthService(mockUserRepo, mockJWTService, new(MockRefreshTokenRepository), new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyBlock, time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+	assert.Nil(t, result)
+	mockJWTService.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_UnverifiedRestricted(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyRestrict, time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockUserRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, mock.MatchedBy(func(scopes []string) bool {
+		return len(scopes) == 0
+	})).Return("jwt-token", nil)
+	// Семейство запоминает запрошенные scopes, чтобы получить их после подтверждения адреса
+	mockRefreshRepo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token *models.RefreshToken) bool {
+		return token.Scope == "profile account"
+	})).Return(nil)
+
+	// Act
+	result, err := authService.Login(context.Background(), &models.LoginRequest{Email: user.Email, Password: "password123"})
+
+	// Assert: вход разрешен, но токен без scopes
+	assert.NoError(t, err)
+	assert.Equal(t, "jwt-token", result.Token)
+	assert.Empty(t, result.Scope)
+	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_RestrictedScopesReturnAfterVerification(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockJWTService := new(MockJWTService)
+	mockRefreshRepo := new(MockRefreshTokenRepository)
+	authService := NewAuthService(mockUserRepo, mockJWTService, mockRefreshRepo, new(MockTokenRevoker), new(MockEmailVerifier), new(MockLoginLockout), new(MockMFAChallenger), VerificationPolicyRestrict, time.Hour)
+
+	// Семейство выдано до подтверждения адреса; теперь адрес подтвержден
+	user := newUnverifiedUser(t)
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		Scope:     "profile",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockRefreshRepo.On("GetRefreshTokenByHash", mock.Anything, auth.HashOpaqueToken("refresh-token")).Return(stored, nil)
+	mockUserRepo.On("GetUserByID", mock.Anything, user.ID.String()).Return(user, nil)
+	mockJWTService.On("GenerateToken", user.ID.String(), user.Email, 0, []string{"profile"}).Return("jwt-token", nil)
+	mockRefreshRepo.On("RotateRefreshToken", mock.Anything, stored.ID, mock.MatchedBy(func(next *models.RefreshToken) bool {
+		return next.Scope == "profile"
+	})).Return(nil)
+
+	// Act
+	result, err := authService.Refresh(context.Background(), "refresh-token")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "profile", result.Scope)
+	mockJWTService.AssertExpectations(t)
+	mockRefreshRepo.AssertExpectations(t)
+}
+
+func TestEmailVerificationService_VerifyEmail(t *testing.T) {
+	// Arrange
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockUserTokenRepository)
+	verificationService := NewEmailVerificationService(mockUserRepo, mockTokenRepo, new(MockMailer), testTokenSecret, "https://auth.example.com", time.Hour)
+
+	token, tokenHash, err := auth.NewSignedToken([]byte(testTokenSecret), models.UserTokenVerifyEmail)
+	require.NoError(t, err)
+
+	stored := &models.UserToken{
+		UserID:    uuid.New(),
+		Purpose:   models.UserTokenVerifyEmail,
+		Email:     "test@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	// Настраиваем моки: второй раз тот же токен уже использован
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenVerifyEmail, tokenHash).Return(stored, nil).Once()
+	mockTokenRepo.On("ConsumeUserToken", mock.Anything, models.UserTokenVerifyEmail, tokenHash).Return(nil, postgres.ErrUserTokenNotFound)
+	mockUserRepo.On("MarkEmailVerified", mock.Anything, stored.UserID, stored.Email).Return(nil).Once()
+
+	// Act & Assert
+	assert.NoError(t, verificationService.VerifyEmail(context.Background(), token))
+	assert.ErrorIs(t, verificationService.VerifyEmail(context.Background(), token), ErrInvalidVerificationToken)
+
+	// Поддельный токен отклоняется без обращения к БД
+	assert.ErrorIs(t, verificationService.VerifyEmail(context.Background(), "forged.token"), ErrInvalidVerificationToken)
+
+	mockTokenRepo.AssertNumberOfCalls(t, "ConsumeUserToken", 2)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestEmailVerificationService_SendVerification(t *testing.T) {
+	// Arrange
+	mockTokenRepo := new(MockUserTokenRepository)
+	mockMailer := new(MockMailer)
+	verificationService := NewEmailVerificationService(new(MockUserRepository), mockTokenRepo, mockMailer, testTokenSecret, "https://auth.example.com/", time.Hour)
+
+	user := newUnverifiedUser(t)
+	mockTokenRepo.On("CreateUserToken", mock.Anything, mock.MatchedBy(func(token *models.UserToken) bool {
+		return token.UserID == user.ID && token.Email == user.Email && token.Purpose == models.UserTokenVerifyEmail
+	})).Return(nil)
+	mockMailer.On("SendVerificationEmailAsync", user.Email, mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, "https://auth.example.com/auth/verify-email?token=")
+	})).Return()
+
+	// Act
+	err := verificationService.SendVerification(context.Background(), user)
+
+	// Assert
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
\ No newline at end of file
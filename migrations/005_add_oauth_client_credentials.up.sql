@@ -0,0 +1,3 @@
+-- Конфиденциальные клиенты (сервисы) аутентифицируются секретом; у публичных secret_hash = NULL
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS secret_hash VARCHAR(255);
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
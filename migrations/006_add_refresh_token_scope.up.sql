@@ -0,0 +1,2 @@
+-- Scopes, выданные при логине, переходят ко всем токенам семейства при ротации
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT '';
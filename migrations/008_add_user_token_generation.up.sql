@@ -0,0 +1,3 @@
+-- Поколение токенов пользователя: увеличивается при смене пароля,
+-- access токены с меньшим значением claim gen отклоняются
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_generation INTEGER NOT NULL DEFAULT 0;
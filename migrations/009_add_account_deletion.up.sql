@@ -0,0 +1,6 @@
+-- Удаление аккаунта: после deleted_at вход запрещен, до purge_after удаление можно отменить,
+-- после - строка и все зависимые данные (ON DELETE CASCADE) удаляются фоновой задачей
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_after TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after ON users(purge_after) WHERE purge_after IS NOT NULL;
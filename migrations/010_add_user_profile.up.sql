@@ -0,0 +1,6 @@
+-- Редактируемый профиль пользователя; metadata - произвольные данные клиента
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Необязательный логин пользователя; уникален без учета регистра
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(32);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
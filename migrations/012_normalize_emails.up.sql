@@ -0,0 +1,34 @@
+-- Адреса хранятся в канонической форме: без пробелов, в Unicode NFC и нижнем регистре.
+-- Домены IDN миграция оставляет в Unicode: в punycode их переписывает команда
+-- cmd/email-report -apply. До ее запуска вход не ломается - поиск пользователя по
+-- адресу принимает и прежнюю Unicode форму домена.
+-- Если после нормализации адрес принадлежит нескольким аккаунтам, миграция прерывается
+-- со списком конфликтов - их нужно разрешить вручную до повторного запуска.
+DO $$
+DECLARE
+    duplicate RECORD;
+    duplicates INTEGER := 0;
+BEGIN
+    FOR duplicate IN
+        SELECT LOWER(normalize(TRIM(email), NFC)) AS canonical,
+               string_agg(id::text || ' <' || email || '>', ', ' ORDER BY created_at) AS accounts
+        FROM users
+        GROUP BY 1
+        HAVING COUNT(*) > 1
+    LOOP
+        duplicates := duplicates + 1;
+        RAISE NOTICE 'duplicate email %: %', duplicate.canonical, duplicate.accounts;
+    END LOOP;
+
+    IF duplicates > 0 THEN
+        RAISE EXCEPTION '% email addresses belong to several accounts after normalization', duplicates;
+    END IF;
+END $$;
+
+UPDATE users SET email = LOWER(normalize(TRIM(email), NFC))
+WHERE email <> LOWER(normalize(TRIM(email), NFC));
+
+UPDATE user_tokens SET email = LOWER(normalize(TRIM(email), NFC))
+WHERE email <> LOWER(normalize(TRIM(email), NFC));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
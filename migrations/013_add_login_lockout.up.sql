@@ -0,0 +1,5 @@
+-- Блокировка входа после серии неудачных попыток. lockout_count - число блокировок
+-- подряд: каждая следующая длится вдвое дольше, успешный вход обнуляет счетчики
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lockout_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
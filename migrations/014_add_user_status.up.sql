@@ -0,0 +1,26 @@
+-- Статус аккаунта: pending -> active -> suspended/deleted. status_reason и status_changed_at
+-- описывают последний переход, полная история хранится в user_status_history
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'active', 'suspended', 'deleted'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+-- Статус существующих аккаунтов выводится из отметок подтверждения и удаления
+UPDATE users SET status = CASE
+    WHEN deleted_at IS NOT NULL THEN 'deleted'
+    WHEN email_verified_at IS NOT NULL THEN 'active'
+    ELSE 'pending'
+END;
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
+
+CREATE TABLE IF NOT EXISTS user_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_status_history_user_id ON user_status_history(user_id, created_at);
@@ -0,0 +1,11 @@
+-- Роль пользователя: admin получает доступ к /admin. Первого администратора
+-- назначают вручную: UPDATE users SET role = 'admin' WHERE email = '...'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin'));
+
+-- Администратор может потребовать смену пароля: вход по старому паролю запрещен до сброса
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Постраничный список пользователей (курсор по created_at, id) и поиск по префиксу email
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users(email text_pattern_ops);
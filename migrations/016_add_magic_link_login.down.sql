@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS email_requests;
+
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS binding_hash;
+
+-- Пустой хеш не совпадает ни с одним паролем: аккаунт без пароля сможет войти только после сброса
+UPDATE users SET password_hash = '' WHERE password_hash IS NULL;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
@@ -0,0 +1,17 @@
+-- Аккаунты без пароля входят по ссылке из письма
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
+
+-- Хеш секрета устройства, запросившего ссылку: войти по пересланной ссылке
+-- с другого устройства нельзя
+ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS binding_hash VARCHAR(64);
+
+-- Запросы писем на адрес для ограничения частоты отправки.
+-- Записи хранятся для всех адресов, в том числе незарегистрированных.
+CREATE TABLE IF NOT EXISTS email_requests (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_requests_email_purpose ON email_requests(email, purpose, created_at);
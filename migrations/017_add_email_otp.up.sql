@@ -0,0 +1,14 @@
+-- Одноразовые коды из писем (вход, второй фактор, подтверждение действий).
+-- Хранится только HMAC кода; у пользователя не больше одного кода на назначение
+-- (новый код заменяет прежний), attempts - сколько раз код уже проверяли
+CREATE TABLE IF NOT EXISTS email_otps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, purpose)
+);
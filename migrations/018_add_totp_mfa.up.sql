@@ -0,0 +1,7 @@
+-- Двухфакторная аутентификация по TOTP. totp_secret хранится зашифрованным
+-- ключом сервера и появляется при подключении; TOTP включена, когда пользователь
+-- подтвердил первый код (totp_enabled_at). totp_last_used_step - период последнего
+-- принятого кода: код не принимается повторно
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_used_step BIGINT NOT NULL DEFAULT 0;
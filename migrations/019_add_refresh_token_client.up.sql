@@ -0,0 +1,3 @@
+-- OAuth клиент, которому выдано семейство refresh токенов; NULL - токены
+-- первой стороны (/auth/login). Обменять токен может только тот же клиент
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) REFERENCES oauth_clients(client_id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+-- Передавался ли redirect_uri в запросе авторизации: тогда при обмене кода
+-- он обязателен и должен совпасть (RFC 6749, раздел 4.1.3)
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS redirect_uri_provided BOOLEAN NOT NULL DEFAULT FALSE;
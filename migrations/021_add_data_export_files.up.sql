@@ -0,0 +1,12 @@
+-- Выгрузки данных, которые собраны в фоне и ждут скачивания по ссылке из письма.
+-- Снимок привязан к токену ссылки: удаляется вместе с ним (новая ссылка заменяет
+-- прежнюю), после скачивания или по истечении срока
+CREATE TABLE IF NOT EXISTS data_export_files (
+    token_id UUID PRIMARY KEY REFERENCES user_tokens(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    data BYTEA NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_export_files_expires_at ON data_export_files(expires_at);